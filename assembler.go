@@ -0,0 +1,317 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Assembler assembles regular expressions incrementally.
+// The zero value is an empty assembler ready to use, which
+// parses the patterns in the same way as Join.
+type Assembler struct {
+	opts  *Options
+	m     merger
+	sub   []*syntax.Regexp
+	units []unit
+	n, id int
+	cache *syntax.Regexp // the result of replay with Canonical
+}
+
+// NewAssembler creates a new assembler with the options.
+// The Dialect option is used only by JoinWithOptions.
+func NewAssembler(opts Options) *Assembler {
+	if opts.Flags == 0 && !opts.POSIX {
+		opts.Flags = DefaultFlags
+	}
+	return &Assembler{opts: &opts, m: merger{ordered: opts.LeftmostFirst}}
+}
+
+func (a *Assembler) options() *Options {
+	if a.opts == nil {
+		return &defaultOptions
+	}
+	return a.opts
+}
+
+// unit is an alternative of an added pattern. The assembler keeps the
+// original tree and the index of the branch it is merged into, so that
+// the branches can be rebuilt when a pattern is removed.
+type unit struct {
+	id      int
+	pattern string
+	re      *syntax.Regexp
+	at      int
+}
+
+// Add parses the pattern and adds it to the assembler. The error is of
+// type *PatternError, and the index is counted including the failed ones.
+func (a *Assembler) Add(pattern string) error {
+	opts := a.options()
+	if opts.Captures == CaptureInput {
+		if name := opts.name(a.id); !isCaptureName(name) {
+			a.id++
+			return &PatternError{a.id - 1, pattern, &syntax.Error{
+				Code: syntax.ErrInvalidNamedCapture, Expr: name,
+			}}
+		}
+	}
+	if opts.Literal {
+		a.add(pattern, literal(pattern, opts.Flags))
+		return nil
+	}
+	r, err := syntax.Parse(pattern, opts.Flags)
+	if err != nil {
+		a.id++
+		return &PatternError{a.id - 1, pattern, err.(*syntax.Error)}
+	}
+	a.add(pattern, r)
+	return nil
+}
+
+func literal(s string, flags syntax.Flags) *syntax.Regexp {
+	if s == "" {
+		return &syntax.Regexp{Op: syntax.OpEmptyMatch, Flags: flags}
+	}
+	rs := []rune(s)
+	if flags&syntax.FoldCase != 0 {
+		for i, r := range rs {
+			// use the minimum rune in the orbit like the parser
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				rs[i] = min(rs[i], f)
+			}
+		}
+	}
+	return &syntax.Regexp{Op: syntax.OpLiteral, Flags: flags, Rune: rs}
+}
+
+// AddRegexp adds the parsed regular expression to the assembler.
+// The argument is not modified by the assembler. Unlike Add,
+// the name of the group wrapping the pattern is not validated.
+func (a *Assembler) AddRegexp(r *syntax.Regexp) {
+	a.add(r.String(), clone(r))
+}
+
+func (a *Assembler) add(pattern string, r *syntax.Regexp) {
+	opts := a.options()
+	r = opts.Captures.apply(r, opts.name(a.id))
+	r = normalizeFlags(expandAnyChar(breakLiterals(normalizeAssertions(r))))
+	if opts.ExpandCase {
+		r = expandCase(r)
+	}
+	a.addUnits(pattern, r)
+	a.n, a.id = a.n+1, a.id+1
+}
+
+func (a *Assembler) addUnits(pattern string, r *syntax.Regexp) {
+	if r.Op == syntax.OpAlternate {
+		for _, r := range r.Sub {
+			a.addUnits(pattern, r)
+		}
+		return
+	}
+	u := unit{id: a.id, pattern: pattern, re: clone(r)}
+	a.sub, u.at = a.m.insert(a.sub, 0, r)
+	a.units = append(a.units, u)
+	a.cache = nil
+}
+
+// Remove removes the pattern from the assembler, and reports whether the
+// pattern was found. All the patterns equal to the argument are removed.
+// The patterns added by AddRegexp are compared by their String method.
+// The result is the same as assembling the remaining patterns from scratch.
+func (a *Assembler) Remove(pattern string) bool {
+	k, single := -1, true
+	for _, u := range a.units {
+		if u.pattern == pattern {
+			if k >= 0 && u.at != k {
+				single = false
+			}
+			if k < 0 || u.at < k {
+				k = u.at
+			}
+		}
+	}
+	if k < 0 {
+		return false
+	}
+	units, id := a.units[:0], -1
+	for _, u := range a.units {
+		if u.pattern == pattern {
+			if u.id != id {
+				a.n, id = a.n-1, u.id
+			}
+			continue
+		}
+		units = append(units, u)
+	}
+	clear(a.units[len(units):])
+	a.units, a.cache = units, nil
+	if a.m.ordered {
+		// with LeftmostFirst, the removed one may have blocked the merging
+		a.reinsert(0)
+	} else if !single || !a.rebuild(k) {
+		a.reinsert(k)
+	}
+	return true
+}
+
+// rebuild rebuilds the branch from its own units, keeping the other branches.
+// It reports false if the branch would change the other branches, like when
+// a unit of a following branch can be merged into the rebuilt one, or the
+// branch would be created after them, or the units do not merge into one.
+//
+//	a|b|xy|bc => [ab]|xy|bc, removing a => bc?|xy
+func (a *Assembler) rebuild(k int) bool {
+	var r *syntax.Regexp
+	var following bool
+	for _, u := range a.units {
+		switch {
+		case u.at < k:
+		case u.at == k:
+			if r == nil {
+				if following {
+					return false
+				}
+				r = clone(u.re)
+				continue
+			}
+			sub, at := a.m.insert([]*syntax.Regexp{r}, 0, clone(u.re))
+			if at > 0 {
+				return false
+			}
+			r = sub[0]
+		default:
+			following = true
+			if r != nil && (r.Equal(u.re) || a.m.mergePrefix(r, clone(u.re)) != nil) {
+				return false
+			}
+		}
+	}
+	if r == nil {
+		a.sub = slices.Delete(a.sub, k, k+1)
+		for i := range a.units {
+			if a.units[i].at > k {
+				a.units[i].at--
+			}
+		}
+		return true
+	}
+	a.sub[k] = r
+	return true
+}
+
+// reinsert rebuilds the branches from the k-th one. A pattern is merged into
+// the first branch it can be merged into, so the branches before the k-th
+// one are kept as they are, and the following patterns never merge into them.
+func (a *Assembler) reinsert(k int) {
+	a.sub = a.sub[:k]
+	for i, u := range a.units {
+		if u.at >= k {
+			a.sub, a.units[i].at = a.m.insert(a.sub, k, clone(u.re))
+		}
+	}
+}
+
+// Len returns the number of patterns in the assembler.
+func (a *Assembler) Len() int {
+	return a.n
+}
+
+// Regexp returns the assembled regular expression.
+// The assembler can be used after calling this method,
+// and the returned tree is not modified by subsequent calls.
+func (a *Assembler) Regexp() *syntax.Regexp {
+	if opts := a.options(); opts.Canonical && !opts.LeftmostFirst {
+		if a.cache == nil {
+			a.cache = a.replay(&a.m)
+		}
+		return clone(a.cache)
+	}
+	sub := make([]*syntax.Regexp, len(a.sub))
+	for i, r := range a.sub {
+		sub[i] = clone(r)
+	}
+	return a.assemble(&a.m, sub)
+}
+
+func (a *Assembler) assemble(m *merger, sub []*syntax.Regexp) *syntax.Regexp {
+	var r *syntax.Regexp
+	if m.ordered {
+		r = m.alternateOrdered(sub)
+	} else {
+		r = m.mergeSuffix(m.alternate(sub...))
+	}
+	if len(sub) == 0 {
+		return r
+	}
+	r = foldAnyChar(r)
+	opts := a.options()
+	if opts.MergeCase && !opts.ExpandCase {
+		r = m.mergeCase(r)
+	}
+	if opts.FoldRepeats {
+		r = m.foldRepeats(r)
+	}
+	if opts.PreferLongest && !opts.LeftmostFirst {
+		r = preferLongest(r)
+	}
+	r = m.derive(opts.Anchor.wrap(r), r)
+	numberCaptures(r, 0)
+	return r
+}
+
+// replay assembles the units from scratch. The units are sorted by their
+// patterns with Canonical, and the same ones are merged into the first one.
+func (a *Assembler) replay(m *merger) *syntax.Regexp {
+	opts := a.options()
+	units, canonical := a.units, opts.Canonical && !opts.LeftmostFirst
+	if canonical {
+		keys := make(map[*syntax.Regexp]string, len(units))
+		for _, u := range units {
+			keys[u.re] = u.re.String()
+		}
+		units = slices.Clone(units)
+		slices.SortStableFunc(units, func(u, v unit) int {
+			return strings.Compare(keys[u.re], keys[v.re])
+		})
+	}
+	var sub []*syntax.Regexp
+	for i := 0; i < len(units); {
+		r := clone(units[i].re)
+		if m.src != nil {
+			m.mark(r, units[i].id)
+		}
+		j := i + 1
+		for ; canonical && j < len(units) && units[j].re.Equal(units[i].re); j++ {
+			if m.src != nil {
+				rr := clone(units[j].re)
+				m.mark(rr, units[j].id)
+				m.absorb(r, rr)
+			}
+		}
+		sub, _ = m.insert(sub, 0, r)
+		i = j
+	}
+	return a.assemble(m, sub)
+}
+
+// String returns the assembled regular expression pattern.
+func (a *Assembler) String() string {
+	return a.Regexp().String()
+}
+
+func clone(r *syntax.Regexp) *syntax.Regexp {
+	rr := *r
+	if r.Sub != nil {
+		rr.Sub = make([]*syntax.Regexp, len(r.Sub))
+		for i, r := range r.Sub {
+			rr.Sub[i] = clone(r)
+		}
+	}
+	if r.Rune != nil {
+		rr.Rune = append([]rune(nil), r.Rune...)
+	}
+	return &rr
+}
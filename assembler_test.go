@@ -0,0 +1,213 @@
+package rassemble
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"testing"
+)
+
+func TestAssembler(t *testing.T) {
+	var a Assembler
+	if got, expected := a.String(), ""; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+	testCases := []struct {
+		pattern  string
+		expected string
+	}{
+		{"abc", "abc"},
+		{"abd", "ab[cd]"},
+		{"bd", "ab[cd]|bd"},
+		{"", "ab[cd]|bd|(?:)"},
+		{"b", "ab[cd]|bd?|(?:)"},
+		{"bcd", "ab[cd]|b(?:c?d)?|(?:)"},
+	}
+	var snapshots []*syntax.Regexp
+	for i, tc := range testCases {
+		if err := a.Add(tc.pattern); err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if got, expected := a.Len(), i+1; got != expected {
+			t.Errorf("expected: %d, got: %d", expected, got)
+		}
+		if got := a.String(); got != tc.expected {
+			t.Errorf("expected: %s, got: %s", tc.expected, got)
+		}
+		snapshots = append(snapshots, a.Regexp())
+	}
+	for i, tc := range testCases {
+		if got := snapshots[i].String(); got != tc.expected {
+			t.Errorf("snapshot %d: expected: %s, got: %s", i, tc.expected, got)
+		}
+	}
+	if err := a.Add("*"); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestAssemblerAddRegexp(t *testing.T) {
+	var a Assembler
+	for _, pattern := range []string{"abc", "(?:abd)+", "abe"} {
+		r, err := syntax.Parse(pattern, syntax.Perl)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		a.AddRegexp(r)
+		if got := r.String(); got != pattern {
+			t.Errorf("argument modified: expected: %s, got: %s", pattern, got)
+		}
+	}
+	if got, expected := a.String(), "ab[ce]|(?:abd)+"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+func TestAssemblerRemove(t *testing.T) {
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, opts := range []Options{
+				{}, {LeftmostFirst: true},
+			} {
+				for _, pattern := range tc.patterns {
+					a, b := NewAssembler(opts), NewAssembler(opts)
+					var patterns []string
+					for _, p := range tc.patterns {
+						if err := a.Add(p); err != nil {
+							t.Fatalf("got an error: %s", err)
+						}
+						if p != pattern {
+							patterns = append(patterns, p)
+							_ = b.Add(p)
+						}
+					}
+					if !a.Remove(pattern) {
+						t.Fatalf("expected %q to be removed", pattern)
+					}
+					if a.Remove(pattern) {
+						t.Fatalf("expected %q not to be removed", pattern)
+					}
+					if got, expected := a.Len(), len(patterns); got != expected {
+						t.Errorf("expected: %d, got: %d", expected, got)
+					}
+					if got, expected := a.String(), b.String(); got != expected {
+						t.Errorf("remove %q: expected: %s, got: %s", pattern, expected, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAssemblerRemoveBranch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		pattern  string
+		expected string
+	}{
+		{
+			name:     "rebuild the branch",
+			patterns: []string{"abc", "abd", "bcd", "bce"},
+			pattern:  "abd",
+			expected: "abc|bc[de]",
+		},
+		{
+			name:     "remove the branch",
+			patterns: []string{"ab", "cd", "ce"},
+			pattern:  "ab",
+			expected: "c[de]",
+		},
+		{
+			name:     "merge the following branch",
+			patterns: []string{"a", "b", "xy", "bc"},
+			pattern:  "a",
+			expected: "bc?|xy",
+		},
+		{
+			name:     "create the branch after the following one",
+			patterns: []string{"ab", "cd", "ae"},
+			pattern:  "ab",
+			expected: "cd|ae",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var a Assembler
+			for _, p := range tc.patterns {
+				if err := a.Add(p); err != nil {
+					t.Fatalf("got an error: %s", err)
+				}
+			}
+			if !a.Remove(tc.pattern) {
+				t.Fatalf("expected %q to be removed", tc.pattern)
+			}
+			if got := a.String(); got != tc.expected {
+				t.Errorf("expected: %s, got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAssemblerRemoveAll(t *testing.T) {
+	var a Assembler
+	patterns := []string{"abc", "abd|bd", "b", "", "abd|bd", "c+"}
+	for _, p := range patterns {
+		if err := a.Add(p); err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+	}
+	removed := map[string]bool{}
+	for _, p := range patterns {
+		if a.Remove(p) == removed[p] {
+			t.Fatalf("unexpected result of removing %q", p)
+		}
+		removed[p] = true
+		var remaining []string
+		for _, p := range patterns {
+			if !removed[p] {
+				remaining = append(remaining, p)
+			}
+		}
+		expected, err := Join(remaining)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if got := a.String(); got != expected {
+			t.Errorf("remove %q: expected: %s, got: %s", p, expected, got)
+		}
+	}
+	if got, expected := a.Len(), 0; got != expected {
+		t.Errorf("expected: %d, got: %d", expected, got)
+	}
+}
+
+func TestAssemblerCanonical(t *testing.T) {
+	a := NewAssembler(Options{Canonical: true})
+	testCases := []struct {
+		pattern  string
+		remove   bool
+		expected string
+	}{
+		{"c", false, "c"},
+		{"ab", false, "ab|c"},
+		{"a", false, "ab?|c"},
+		{"c", true, "ab?"},
+		{"c", false, "ab?|c"},
+		{"a", false, "ab?|c"},
+	}
+	for _, tc := range testCases {
+		if tc.remove {
+			a.Remove(tc.pattern)
+		} else if err := a.Add(tc.pattern); err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		a.Regexp().Sub = nil // the cached tree is not modified
+		if got := a.String(); got != tc.expected {
+			t.Errorf("expected: %s, got: %s", tc.expected, got)
+		}
+	}
+	s := a.SourceMap()
+	if got, expected := fmt.Sprint(s.Sources(s.Regexp.Sub[0])), "[1 2 4]"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
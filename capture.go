@@ -0,0 +1,112 @@
+package rassemble
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"unicode"
+)
+
+// tag wraps the pattern in the named group. The groups wrapping the patterns
+// are marked by the negative index until the groups are numbered.
+func tag(r *syntax.Regexp, name string) *syntax.Regexp {
+	return &syntax.Regexp{
+		Op: syntax.OpCapture, Cap: -1, Name: name, Sub: []*syntax.Regexp{r},
+	}
+}
+
+func isTag(r *syntax.Regexp) bool {
+	return r.Op == syntax.OpCapture && r.Cap < 0
+}
+
+// mergeTag factors the common prefix of the groups wrapping the patterns,
+// keeping the rest of each pattern in the group.
+func (m *merger) mergeTag(r1, r2 *syntax.Regexp) *syntax.Regexp {
+	if isTag(r1) && isTag(r2) && r1.Name == r2.Name {
+		// (?P<x>foo)|(?P<x>bar) => (?P<x>foo|bar)
+		return m.derive(tag(m.alternate(r1.Sub[0], r2.Sub[0]), r1.Name), r1, r2)
+	}
+	sub1, sub2 := tagSub(r1), tagSub(r2)
+	var i int
+	for ; i < len(sub1) && i < len(sub2); i++ {
+		if !sub1[i].Equal(sub2[i]) {
+			break
+		}
+	}
+	if i == 0 {
+		return nil
+	}
+	// (?P<x>foobar)|(?P<y>foobaz) => fooba(?:(?P<x>r)|(?P<y>z))
+	rest1 := m.derive(tagRest(r1, sub1[i:]), r1)
+	rest2 := m.derive(tagRest(r2, sub2[i:]), r2)
+	for j := range i {
+		m.absorb(sub1[j], sub2[j])
+	}
+	return m.derive(concat(
+		append(
+			append(make([]*syntax.Regexp, 0, i+1), sub1[:i]...),
+			m.alternate(rest1, rest2),
+		)...,
+	), r1, r2)
+}
+
+func tagSub(r *syntax.Regexp) []*syntax.Regexp {
+	if isTag(r) {
+		r = r.Sub[0]
+	}
+	switch r.Op {
+	case syntax.OpEmptyMatch:
+		return nil
+	case syntax.OpConcat:
+		return r.Sub
+	default:
+		return []*syntax.Regexp{r}
+	}
+}
+
+func tagRest(r *syntax.Regexp, sub []*syntax.Regexp) *syntax.Regexp {
+	if isTag(r) {
+		return tag(concat(sub...), r.Name)
+	}
+	return concat(sub...)
+}
+
+// numberCaptures numbers the capture groups in the order of the positions.
+func numberCaptures(r *syntax.Regexp, n int) int {
+	if r.Op == syntax.OpCapture {
+		n++
+		r.Cap = n
+	}
+	for _, rr := range r.Sub {
+		n = numberCaptures(rr, n)
+	}
+	return n
+}
+
+func isCaptureName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchNames returns the names of the groups wrapping the patterns which
+// participate in the match, where the regexp is compiled from the assembled
+// pattern and loc is the result of its FindStringSubmatchIndex method.
+func (a *Assembler) MatchNames(re *regexp.Regexp, loc []int) []string {
+	opts, names := a.options(), map[string]bool{}
+	for _, u := range a.units {
+		names[opts.name(u.id)] = true
+	}
+	var matched []string
+	for i, name := range re.SubexpNames() {
+		if names[name] && 2*i < len(loc) && loc[2*i] >= 0 {
+			matched, names[name] = append(matched, name), false
+		}
+	}
+	return matched
+}
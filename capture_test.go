@@ -0,0 +1,62 @@
+package rassemble
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestAssemblerMatchNames(t *testing.T) {
+	a := NewAssembler(Options{
+		Captures: CaptureInput,
+		Names:    []string{"rule_0", "rule_1", "rule_0", "", "rule_4"},
+	})
+	for _, pattern := range []string{"foo(bar)", "foobaz", "(?P<x>qux)", "fo+", "(?P<y>ba[rz])$"} {
+		if err := a.Add(pattern); err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+	}
+	expected := "(?m:f(?:oo(?:(?P<rule_0>(bar))|(?P<rule_1>baz))|(?P<input3>o+))|(?P<rule_0>(?P<x>qux))|(?P<rule_4>(?P<y>ba[rz])$))"
+	if got := a.String(); got != expected {
+		t.Fatalf("expected: %s, got: %s", expected, got)
+	}
+	re := regexp.MustCompile(a.String())
+	for input, expected := range map[string][]string{
+		"foobar": {"rule_0"},
+		"foobaz": {"rule_1"},
+		"qux":    {"rule_0"},
+		"fooo":   {"input3"},
+		"baz":    {"rule_4"},
+		"bar":    {"rule_4"},
+		"":       nil,
+	} {
+		got := a.MatchNames(re, re.FindStringSubmatchIndex(input))
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("%q: expected: %v, got: %v", input, expected, got)
+		}
+	}
+}
+
+func TestJoinSyntaxCaptures(t *testing.T) {
+	r, err := JoinSyntax([]string{"(a)b(c)", "(a)(d)", "(?P<x>e)"})
+	if err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	expected := []string{"", "", "", "", "x"}
+	if got := r.CapNames(); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+	if got, expected := r.MaxCap(), 4; got != expected {
+		t.Errorf("expected: %d, got: %d", expected, got)
+	}
+}
+
+func TestIsCaptureName(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"": false, "rule_1": true, "rule-1": false, "規則": true,
+	} {
+		if got := isCaptureName(name); got != expected {
+			t.Errorf("%q: expected: %t, got: %t", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"slices"
+	"unicode"
+)
+
+// mergeCase merges the successive classes of the case variants into
+// a case-insensitive literal, when the pattern gets shorter.
+func (m *merger) mergeCase(r *syntax.Regexp) *syntax.Regexp {
+	for i, rr := range r.Sub {
+		r.Sub[i] = m.mergeCase(rr)
+	}
+	sub := r.Sub
+	if r.Op != syntax.OpConcat {
+		sub = []*syntax.Regexp{r}
+	}
+	rs := make([]*syntax.Regexp, 0, len(sub))
+	for i := 0; i < len(sub); {
+		var lit []rune
+		var fold bool
+		j := i
+		for ; j < len(sub); j++ {
+			rr := sub[j]
+			if rr.Op == syntax.OpCharClass && isFoldOrbit(rr.Rune) {
+				lit, fold = append(lit, rr.Rune[0]), true
+			} else if rr.Op == syntax.OpLiteral &&
+				(rr.Flags&syntax.FoldCase != 0 || !isFoldSensitive(rr.Rune)) {
+				lit, fold = append(lit, rr.Rune...), fold || rr.Flags&syntax.FoldCase != 0
+			} else {
+				break
+			}
+		}
+		// [Ff][Oo][Oo]1 => (?i:foo1)
+		if fold {
+			rr := &syntax.Regexp{Op: syntax.OpLiteral, Flags: syntax.FoldCase, Rune: lit}
+			if len(rr.String()) < len(concat(sub[i:j]...).String()) {
+				rs, i = append(rs, m.derive(rr, sub[i:j]...)), j
+				continue
+			}
+		}
+		if i == j {
+			j++
+		}
+		rs, i = append(rs, sub[i:j]...), j
+	}
+	if r.Op != syntax.OpConcat {
+		return rs[0]
+	}
+	return m.derive(concat(rs...), r)
+}
+
+// expandCase expands the case-insensitive literals into the classes.
+// The literals should be broken into the runes by breakLiterals.
+func expandCase(r *syntax.Regexp) *syntax.Regexp {
+	// (?i:f) => [Ff]
+	if r.Op == syntax.OpLiteral && r.Flags&syntax.FoldCase != 0 && isFoldSensitive(r.Rune) {
+		return &syntax.Regexp{Op: syntax.OpCharClass, Rune: foldOrbit(r.Rune[0])}
+	}
+	for i, rr := range r.Sub {
+		r.Sub[i] = expandCase(rr)
+	}
+	return r
+}
+
+// isFoldOrbit reports whether the class consists of all the case variants
+// of a rune, like [Ff] but not [Kk] lacking the Kelvin sign.
+func isFoldOrbit(rs []rune) bool {
+	return len(rs) > 0 && isFoldSensitive(rs[:1]) && slices.Equal(rs, foldOrbit(rs[0]))
+}
+
+func foldOrbit(r rune) []rune {
+	rs := []rune{r, r}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		rs = append(rs, f, f)
+	}
+	return charClass(rs).Rune
+}
@@ -4,8 +4,14 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"regexp/syntax"
 	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/itchyny/rassemble-go"
 )
@@ -36,12 +42,35 @@ Version: %s (rev: %s/%s)
 
 Synopsis:
   %% %[1]s re1 re2 ...
+  %% %[1]s -F str1 str2 ...
+  %% %[1]s -range lo-hi ...
 
 Options:
 `, name, version, revision, runtime.Version())
 		fs.PrintDefaults()
 	}
-	var showVersion bool
+	var literal, shorthand, explain, verify, showVersion bool
+	var dialect rassemble.Dialect
+	fs.BoolVar(&literal, "literal", false, "treat the arguments as literal strings")
+	fs.BoolVar(&literal, "F", false, "alias for -literal")
+	fs.TextVar(&dialect, "flavor", rassemble.DialectGo,
+		"output dialect (go, pcre, javascript, python, java, ere)")
+	fs.BoolVar(&shorthand, "shorthand", false, `use the shorthand classes like \d and \pL`)
+	var anchor rassemble.Anchor
+	fs.TextVar(&anchor, "anchor", rassemble.AnchorNone,
+		"anchor the output (none, line, text, word)")
+	var ranges []*syntax.Regexp
+	var rangeArgs []string
+	fs.Func("range", "add the numbers from lo to hi (lo-hi, repeatable)", func(s string) error {
+		r, err := parseRange(s)
+		if err != nil {
+			return err
+		}
+		ranges, rangeArgs = append(ranges, r), append(rangeArgs, "-range "+s)
+		return nil
+	})
+	fs.BoolVar(&explain, "explain", false, "print the input patterns of each alternative")
+	fs.BoolVar(&verify, "verify", false, "verify that the output matches the same strings as the inputs")
 	fs.BoolVar(&showVersion, "version", false, "print version")
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -53,7 +82,10 @@ Options:
 		fmt.Printf("%s %s (rev: %s/%s)\n", name, version, revision, runtime.Version())
 		return exitCodeOK
 	}
-	if len(args) == 0 {
+	args = fs.Args()
+	source := func(i int) string { return "argument " + strconv.Itoa(i+1) }
+	if len(args) == 0 && len(ranges) == 0 {
+		source = func(i int) string { return "<stdin>:" + strconv.Itoa(i+1) }
 		s := bufio.NewScanner(os.Stdin)
 		for s.Scan() {
 			args = append(args, s.Text())
@@ -63,11 +95,136 @@ Options:
 			return exitCodeErr
 		}
 	}
-	pattern, err := rassemble.Join(args)
+	if literal && len(ranges) == 0 && !explain && !verify {
+		// assemble the literals through a trie, which scales to many literals
+		pattern, err := rassemble.JoinWithOptions(args, rassemble.Options{
+			Literal: true, Anchor: anchor, Dialect: dialect, Shorthand: shorthand,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return exitCodeErr
+		}
+		fmt.Println(pattern)
+		return exitCodeOK
+	}
+	flags := rassemble.DefaultFlags
+	if shorthand {
+		// accept the Unicode classes like \pL as they are in the output
+		flags |= syntax.UnicodeGroups
+	}
+	a := rassemble.NewAssembler(rassemble.Options{Flags: flags, Literal: literal, Anchor: anchor})
+	var failed bool
+	for i, arg := range args {
+		if err := a.Add(arg); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", name, source(i), err.(*rassemble.PatternError).Err)
+			failed = true
+		}
+	}
+	if failed {
+		return exitCodeErr
+	}
+	patterns := make([]string, 0, len(args)+len(ranges))
+	for _, arg := range args {
+		if literal {
+			arg = regexp.QuoteMeta(arg)
+		} else if flags != rassemble.DefaultFlags {
+			// Verify parses the patterns with DefaultFlags
+			r, _ := syntax.Parse(arg, flags)
+			arg = r.String()
+		}
+		patterns = append(patterns, anchorPattern(arg, anchor))
+	}
+	for _, r := range ranges {
+		a.AddRegexp(r)
+		patterns = append(patterns, anchorPattern(r.String(), anchor))
+	}
+	args = append(args, rangeArgs...)
+	render := rassemble.Render
+	if shorthand {
+		render = rassemble.RenderShorthand
+	}
+	pattern, err := render(a.Regexp(), dialect)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 		return exitCodeErr
 	}
 	fmt.Println(pattern)
+	if verify {
+		// verify the pattern in Go, which is equivalent in the other dialects
+		if err := rassemble.Verify(patterns, a.String()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: verify: %s\n", name, err)
+			return exitCodeErr
+		}
+	}
+	if explain {
+		printSourceMap(os.Stdout, a.SourceMap(), args, func(r *syntax.Regexp) string {
+			// the parts of a renderable pattern are renderable
+			pattern, _ := render(r, dialect)
+			return pattern
+		})
+	}
 	return exitCodeOK
 }
+
+// printSourceMap prints the alternatives indented by the nesting level,
+// along with the input patterns they derive from.
+func printSourceMap(w io.Writer, s *rassemble.SourceMap, args []string, render func(*syntax.Regexp) string) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	defer tw.Flush()
+	var walk func(*syntax.Regexp, int)
+	walk = func(r *syntax.Regexp, depth int) {
+		if r.Op != syntax.OpAlternate {
+			for _, r := range r.Sub {
+				walk(r, depth)
+			}
+			return
+		}
+		for _, r := range r.Sub {
+			pattern := render(r)
+			var sources []string
+			for _, i := range s.Sources(r) {
+				sources = append(sources, strconv.Quote(args[i]))
+			}
+			fmt.Fprintf(tw, "%s%s\t%s\n", strings.Repeat("  ", depth), pattern, strings.Join(sources, " "))
+			walk(r, depth+1)
+		}
+	}
+	r := s.Regexp
+	if r.Op != syntax.OpAlternate {
+		r = &syntax.Regexp{Op: syntax.OpAlternate, Sub: []*syntax.Regexp{r}}
+	}
+	walk(r, 0)
+}
+
+// anchorPattern anchors the input pattern in the same way as the output.
+func anchorPattern(pattern string, anchor rassemble.Anchor) string {
+	switch anchor {
+	case rassemble.AnchorLine:
+		return `(?m:^)(?:` + pattern + `)(?m:$)`
+	case rassemble.AnchorText:
+		return `\A(?:` + pattern + `)\z`
+	case rassemble.AnchorWord:
+		return `\b(?:` + pattern + `)\b`
+	default:
+		return pattern
+	}
+}
+
+// parseRange parses lo-hi, where the numbers can be negative.
+func parseRange(s string) (*syntax.Regexp, error) {
+	for i := 1; i < len(s); i++ {
+		if s[i] != '-' {
+			continue
+		}
+		lo, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			continue
+		}
+		hi, err := strconv.ParseInt(s[i+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		return rassemble.Range(lo, hi, rassemble.RangeOptions{})
+	}
+	return nil, fmt.Errorf("invalid range: %s", s)
+}
@@ -0,0 +1,471 @@
+package rassemble
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dialect is the syntax of the output pattern.
+type Dialect int
+
+// Output dialects.
+const (
+	DialectGo         Dialect = iota // Go (RE2) syntax
+	DialectPCRE                      // PCRE in UTF mode
+	DialectJavaScript                // JavaScript with u flag
+	DialectPython                    // Python re module
+	DialectJava                      // Java java.util.regex
+	DialectERE                       // POSIX extended regular expression
+)
+
+var dialectNames = [...]string{"go", "pcre", "javascript", "python", "java", "ere"}
+
+func (d Dialect) String() string {
+	if 0 <= d && int(d) < len(dialectNames) {
+		return dialectNames[d]
+	}
+	return "Dialect(" + strconv.Itoa(int(d)) + ")"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Dialect) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Dialect) UnmarshalText(text []byte) error {
+	for i, name := range dialectNames {
+		if strings.EqualFold(string(text), name) {
+			*d = Dialect(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown dialect: %s", text)
+}
+
+// Render renders the regular expression in the dialect. It reports an error
+// if the regular expression contains a construct the dialect does not support.
+// The semantics of \b and \B follow the dialect.
+func Render(r *syntax.Regexp, d Dialect) (string, error) {
+	return render(r, d, false)
+}
+
+func render(r *syntax.Regexp, d Dialect, shorthand bool) (string, error) {
+	switch d {
+	case DialectGo:
+		if !shorthand {
+			return r.String(), nil
+		}
+		fallthrough
+	case DialectPCRE, DialectJavaScript, DialectPython, DialectJava, DialectERE:
+		w := &renderer{dialect: d, shorthand: shorthand}
+		w.write(r)
+		if w.err != nil {
+			return "", w.err
+		}
+		return w.String(), nil
+	default:
+		return "", fmt.Errorf("unknown dialect: %d", d)
+	}
+}
+
+type renderer struct {
+	strings.Builder
+	dialect   Dialect
+	shorthand bool
+	err       error
+}
+
+func (w *renderer) unsupported(construct string) {
+	if w.err == nil {
+		w.err = fmt.Errorf("%s is not supported in %s", construct, w.dialect)
+	}
+}
+
+func (w *renderer) write(r *syntax.Regexp) {
+	switch r.Op {
+	case syntax.OpNoMatch:
+		switch w.dialect {
+		case DialectGo:
+			w.WriteString(`[^\x00-\x{10FFFF}]`)
+		case DialectERE:
+			w.unsupported("no match")
+		default:
+			w.WriteString(`(?!)`)
+		}
+	case syntax.OpEmptyMatch:
+		w.group(nil)
+	case syntax.OpLiteral:
+		w.literal(r.Rune, r.Flags&syntax.FoldCase != 0)
+	case syntax.OpCharClass:
+		w.charClass(r.Rune)
+	case syntax.OpAnyCharNotNL:
+		switch w.dialect {
+		case DialectGo, DialectPython:
+			w.WriteString(`.`)
+		case DialectERE:
+			w.WriteString("[^\n]")
+		default:
+			w.WriteString(`[^\n]`)
+		}
+	case syntax.OpAnyChar:
+		switch w.dialect {
+		case DialectJavaScript:
+			w.WriteString(`[\s\S]`)
+		case DialectERE:
+			w.WriteString(`.`)
+		default:
+			w.WriteString(`(?s:.)`)
+		}
+	case syntax.OpBeginLine:
+		switch w.dialect {
+		case DialectGo, DialectPython:
+			w.WriteString(`(?m:^)`)
+		case DialectPCRE, DialectJavaScript, DialectJava:
+			// ^ in the multiline mode of PCRE does not match after the final newline
+			w.WriteString(`(?<![^\n])`)
+		default:
+			w.unsupported("beginning of line")
+		}
+	case syntax.OpEndLine:
+		switch w.dialect {
+		case DialectGo, DialectPCRE, DialectPython:
+			w.WriteString(`(?m:$)`)
+		case DialectJavaScript:
+			// (?![^\n]) matches inside a surrogate pair in V8
+			w.WriteString(`(?=\n|$)`)
+		case DialectJava:
+			w.WriteString(`(?![^\n])`)
+		default:
+			w.unsupported("end of line")
+		}
+	case syntax.OpBeginText:
+		switch w.dialect {
+		case DialectJavaScript, DialectERE:
+			w.WriteString(`^`)
+		default:
+			w.WriteString(`\A`)
+		}
+	case syntax.OpEndText:
+		switch w.dialect {
+		case DialectJavaScript, DialectERE:
+			w.WriteString(`$`)
+		case DialectPython:
+			w.WriteString(`\Z`)
+		default:
+			w.WriteString(`\z`)
+		}
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		if w.dialect == DialectERE {
+			w.unsupported("word boundary")
+		} else if r.Op == syntax.OpWordBoundary {
+			w.WriteString(`\b`)
+		} else {
+			w.WriteString(`\B`)
+		}
+	case syntax.OpCapture:
+		w.WriteString(`(`)
+		if r.Name != "" {
+			w.captureName(r.Name)
+		}
+		if r.Sub[0].Op != syntax.OpEmptyMatch {
+			w.write(r.Sub[0])
+		}
+		w.WriteString(`)`)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		// \b? => (?:\b)?, since the assertions cannot be repeated in some dialects
+		if sub := r.Sub[0]; sub.Op > syntax.OpCapture ||
+			sub.Op == syntax.OpLiteral && len(sub.Rune) > 1 ||
+			sub.Op >= syntax.OpBeginLine && sub.Op <= syntax.OpNoWordBoundary {
+			w.group(sub)
+		} else {
+			w.write(sub)
+		}
+		switch r.Op {
+		case syntax.OpStar:
+			w.WriteString(`*`)
+		case syntax.OpPlus:
+			w.WriteString(`+`)
+		case syntax.OpQuest:
+			w.WriteString(`?`)
+		default:
+			w.WriteString(`{` + strconv.Itoa(r.Min))
+			if r.Max != r.Min {
+				w.WriteString(`,`)
+				if r.Max >= 0 {
+					w.WriteString(strconv.Itoa(r.Max))
+				}
+			}
+			w.WriteString(`}`)
+		}
+		if r.Flags&syntax.NonGreedy != 0 {
+			if w.dialect == DialectERE {
+				w.unsupported("non-greedy repetition")
+			}
+			w.WriteString(`?`)
+		}
+	case syntax.OpConcat:
+		for i := 0; i < len(r.Sub); i++ {
+			switch sub := r.Sub[i]; sub.Op {
+			case syntax.OpLiteral:
+				// render successive case-insensitive literals in one group
+				rs := sub.Rune
+				for ; i+1 < len(r.Sub) && r.Sub[i+1].Op == syntax.OpLiteral &&
+					r.Sub[i+1].Flags&syntax.FoldCase == sub.Flags&syntax.FoldCase; i++ {
+					rs = append(rs[:len(rs):len(rs)], r.Sub[i+1].Rune...)
+				}
+				w.literal(rs, sub.Flags&syntax.FoldCase != 0)
+			case syntax.OpAlternate:
+				w.group(sub)
+			default:
+				w.write(sub)
+			}
+		}
+	case syntax.OpAlternate:
+		for i, sub := range r.Sub {
+			if i > 0 {
+				w.WriteString(`|`)
+			}
+			w.write(sub)
+		}
+	}
+}
+
+func (w *renderer) group(r *syntax.Regexp) {
+	if w.dialect == DialectERE {
+		w.WriteString(`(`)
+	} else {
+		w.WriteString(`(?:`)
+	}
+	if r != nil {
+		w.write(r)
+	}
+	w.WriteString(`)`)
+}
+
+func (w *renderer) captureName(name string) {
+	switch w.dialect {
+	case DialectPython:
+		w.WriteString(`?P<` + name + `>`)
+	case DialectJava:
+		for i, r := range name {
+			if !(r < utf8.RuneSelf && unicode.IsLetter(r) ||
+				i > 0 && '0' <= r && r <= '9') {
+				w.unsupported("capture name " + strconv.Quote(name))
+				break
+			}
+		}
+		fallthrough
+	case DialectGo, DialectPCRE, DialectJavaScript:
+		w.WriteString(`?<` + name + `>`)
+	default:
+		w.unsupported("named capture")
+	}
+}
+
+func (w *renderer) literal(rs []rune, foldCase bool) {
+	if foldCase && isFoldSensitive(rs) {
+		switch w.dialect {
+		case DialectGo, DialectPCRE, DialectPython:
+			w.WriteString(`(?i:`)
+		case DialectJava:
+			w.WriteString(`(?iu:`)
+		default:
+			w.unsupported("case-insensitive match")
+			return
+		}
+		defer w.WriteString(`)`)
+	}
+	for _, r := range rs {
+		w.escape(r, false)
+	}
+}
+
+func isFoldSensitive(rs []rune) bool {
+	for _, r := range rs {
+		if unicode.SimpleFold(r) != r {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *renderer) charClass(rs []rune) {
+	if len(rs) == 0 {
+		w.write(&syntax.Regexp{Op: syntax.OpNoMatch})
+		return
+	}
+	if slices.Equal(normalizeClass(rs), []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}) {
+		// [^\n] => .
+		w.write(&syntax.Regexp{Op: syntax.OpAnyCharNotNL})
+		return
+	}
+	// use the shorter one, or the negated one containing both ends
+	// [\x00-\t\v-\x{10FFFF}] => [^\n], [\x01-\x{10FFFF}] => [^\x00]
+	s := w.class(rs, false)
+	if xs := negateClass(rs); len(xs) > 0 {
+		if t := w.class(xs, true); len(t) < len(s) || len(t) == len(s) &&
+			rs[0] == 0 && rs[len(rs)-1] == unicode.MaxRune {
+			s = t
+		}
+	}
+	w.WriteString(s)
+}
+
+func (w *renderer) class(rs []rune, negated bool) string {
+	x := &renderer{dialect: w.dialect, shorthand: w.shorthand}
+	if w.dialect == DialectERE {
+		x.charClassERE(rs, negated)
+	} else if !w.shorthand || !x.shorthandClass(rs, negated) {
+		x.WriteString(`[`)
+		if negated {
+			x.WriteString(`^`)
+		}
+		x.ranges(rs)
+		x.WriteString(`]`)
+	}
+	return x.String()
+}
+
+func (w *renderer) ranges(rs []rune) {
+	for i := 0; i < len(rs); i += 2 {
+		lo, hi := rs[i], rs[i+1]
+		w.escape(lo, true)
+		if lo != hi {
+			if hi != lo+1 {
+				w.WriteString(`-`)
+			}
+			w.escape(hi, true)
+		}
+	}
+}
+
+// In a bracket expression of POSIX ERE, a backslash is not an escape
+// character, so ] is placed at first, and [, ^, - are placed at last.
+func (w *renderer) charClassERE(rs []rune, negated bool) {
+	var middle strings.Builder
+	var specials [4]bool
+	const specialChars = "][^-"
+	for i := 0; i < len(rs); i += 2 {
+		lo, hi := rs[i], rs[i+1]
+		for ; lo <= hi; lo++ {
+			if j := strings.IndexRune(specialChars, lo); j >= 0 {
+				specials[j] = true
+			} else {
+				break
+			}
+		}
+		for ; lo <= hi; hi-- {
+			if j := strings.IndexRune(specialChars, hi); j >= 0 {
+				specials[j] = true
+			} else {
+				break
+			}
+		}
+		if lo <= hi {
+			middle.WriteRune(lo)
+			if lo != hi {
+				if hi != lo+1 {
+					middle.WriteByte('-')
+				}
+				middle.WriteRune(hi)
+			}
+		}
+	}
+	if !negated && !specials[0] && !specials[1] && middle.Len() == 0 && specials[2] {
+		if specials[3] {
+			// [^-] => [-^]
+			w.WriteString(`[-^]`)
+		} else {
+			w.WriteString(`\^`)
+		}
+		return
+	}
+	w.WriteString(`[`)
+	if negated {
+		w.WriteString(`^`)
+	}
+	if specials[0] {
+		w.WriteString(`]`)
+	}
+	w.WriteString(middle.String())
+	for i := 1; i < len(specials); i++ {
+		if specials[i] {
+			w.WriteByte(specialChars[i])
+		}
+	}
+	w.WriteString(`]`)
+}
+
+func negateClass(rs []rune) []rune {
+	xs := make([]rune, 0, len(rs)+2)
+	var lo rune
+	for i := 0; i < len(rs); i += 2 {
+		if lo < rs[i] {
+			xs = append(xs, lo, rs[i]-1)
+		}
+		lo = rs[i+1] + 1
+	}
+	if lo <= unicode.MaxRune {
+		xs = append(xs, lo, unicode.MaxRune)
+	}
+	return xs
+}
+
+func (w *renderer) escape(r rune, inClass bool) {
+	var meta string
+	switch {
+	case w.dialect == DialectERE:
+		if !inClass && strings.ContainsRune(`\^.[$()|*+?{`, r) {
+			w.WriteByte('\\')
+		}
+		w.WriteRune(r)
+		return
+	case !inClass:
+		meta = `\.+*?()|[]{}^$`
+		if w.dialect == DialectJavaScript {
+			meta += `/`
+		}
+	case w.dialect == DialectJava:
+		meta = `\[]^-&`
+	case w.dialect == DialectPython:
+		meta = `\[]^-&~|`
+	default:
+		meta = `\[]^-`
+	}
+	if unicode.IsPrint(r) {
+		if strings.ContainsRune(meta, r) {
+			w.WriteByte('\\')
+		}
+		w.WriteRune(r)
+		return
+	}
+	switch r {
+	case '\t':
+		w.WriteString(`\t`)
+	case '\n':
+		w.WriteString(`\n`)
+	case '\f':
+		w.WriteString(`\f`)
+	case '\r':
+		w.WriteString(`\r`)
+	default:
+		switch {
+		case r < 0x100:
+			w.WriteString(fmt.Sprintf(`\x%02x`, r))
+		case w.dialect == DialectJavaScript:
+			w.WriteString(fmt.Sprintf(`\u{%x}`, r))
+		case w.dialect == DialectPython && r <= 0xffff:
+			w.WriteString(fmt.Sprintf(`\u%04x`, r))
+		case w.dialect == DialectPython:
+			w.WriteString(fmt.Sprintf(`\U%08x`, r))
+		default:
+			w.WriteString(fmt.Sprintf(`\x{%x}`, r))
+		}
+	}
+}
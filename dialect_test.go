@@ -0,0 +1,570 @@
+package rassemble
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		expected map[Dialect]string
+	}{
+		{
+			name:     "literals",
+			patterns: []string{"abc", "abd", `a\.c`, `a\+c`},
+			expected: map[Dialect]string{
+				DialectGo:         `a(?:b[cd]|[\+\.]c)`,
+				DialectPCRE:       `a(?:b[cd]|[+.]c)`,
+				DialectJavaScript: `a(?:b[cd]|[+.]c)`,
+				DialectPython:     `a(?:b[cd]|[+.]c)`,
+				DialectJava:       `a(?:b[cd]|[+.]c)`,
+				DialectERE:        `a(b[cd]|[+.]c)`,
+			},
+		},
+		{
+			name:     "meta characters",
+			patterns: []string{`\.\+\*\?\(\)\|\[\]\{\}\^\$/\\`},
+			expected: map[Dialect]string{
+				DialectGo:         `\.\+\*\?\(\)\|\[\]\{\}\^\$/\\`,
+				DialectPCRE:       `\.\+\*\?\(\)\|\[\]\{\}\^\$/\\`,
+				DialectJavaScript: `\.\+\*\?\(\)\|\[\]\{\}\^\$\/\\`,
+				DialectPython:     `\.\+\*\?\(\)\|\[\]\{\}\^\$/\\`,
+				DialectJava:       `\.\+\*\?\(\)\|\[\]\{\}\^\$/\\`,
+				DialectERE:        `\.\+\*\?\(\)\|\[]\{}\^\$/\\`,
+			},
+		},
+		{
+			name:     "character classes",
+			patterns: []string{`[\]\-^&~|]`, `[\[\\]`},
+			expected: map[Dialect]string{
+				DialectGo:         `[&\-\[-\^\|~]`,
+				DialectPCRE:       `[&\-\[-\^|~]`,
+				DialectJavaScript: `[&\-\[-\^|~]`,
+				DialectPython:     `[\&\-\[-\^\|\~]`,
+				DialectJava:       `[\&\-\[-\^|~]`,
+				DialectERE:        `[]&\|~[^-]`,
+			},
+		},
+		{
+			name:     "negated character class",
+			patterns: []string{`[^a-c]`, `x`},
+			expected: map[Dialect]string{
+				DialectGo:         `[^a-c]`,
+				DialectPCRE:       `[^a-c]`,
+				DialectJavaScript: `[^a-c]`,
+				DialectPython:     `[^a-c]`,
+				DialectJava:       `[^a-c]`,
+				DialectERE:        `[^a-c]`,
+			},
+		},
+		{
+			name:     "shorter negated character class",
+			patterns: []string{`x[\x00\x{10ffff}]`, `y[^\x00\x{10ffff}a]`},
+			expected: map[Dialect]string{
+				DialectGo:         `x[^\x01-\x{10fffe}]|y[\x01-` + "`" + `b-\x{10fffe}]`,
+				DialectPCRE:       `x[\x00\x{10ffff}]|y[^\x00a\x{10ffff}]`,
+				DialectJavaScript: `x[\x00\u{10ffff}]|y[^\x00a\u{10ffff}]`,
+				DialectPython:     `x[\x00\U0010ffff]|y[^\x00a\U0010ffff]`,
+				DialectJava:       `x[\x00\x{10ffff}]|y[^\x00a\x{10ffff}]`,
+				DialectERE:        "x[\x00\U0010ffff]|y[^\x00a\U0010ffff]",
+			},
+		},
+		{
+			name:     "escape sequences",
+			patterns: []string{`\t\n\v\f\r\x00\x{e9}\x{100}\x{1f600}`},
+			expected: map[Dialect]string{
+				DialectGo:         `\t\n\v\f\r\x00éĀ😀`,
+				DialectPCRE:       `\t\n\x0b\f\r\x00éĀ😀`,
+				DialectJavaScript: `\t\n\x0b\f\r\x00éĀ😀`,
+				DialectPython:     `\t\n\x0b\f\r\x00éĀ😀`,
+				DialectJava:       `\t\n\x0b\f\r\x00éĀ😀`,
+				DialectERE:        "\t\n\v\f\r\x00éĀ😀",
+			},
+		},
+		{
+			name:     "escape sequences in character class",
+			patterns: []string{`[\x01-\x1f\x{2028}\x{10fffd}-\x{10fffe}]`},
+			expected: map[Dialect]string{
+				DialectGo:         `[\x01-\x1f\x{2028}\x{10fffd}\x{10fffe}]`,
+				DialectPCRE:       `[\x01-\x1f\x{2028}\x{10fffd}\x{10fffe}]`,
+				DialectJavaScript: `[\x01-\x1f\u{2028}\u{10fffd}\u{10fffe}]`,
+				DialectPython:     `[\x01-\x1f\u2028\U0010fffd\U0010fffe]`,
+				DialectJava:       `[\x01-\x1f\x{2028}\x{10fffd}\x{10fffe}]`,
+				DialectERE:        "[\x01-\x1f\u2028\U0010fffd\U0010fffe]",
+			},
+		},
+		{
+			name:     "dot",
+			patterns: []string{`a.`, `(?s:b.)`},
+			expected: map[Dialect]string{
+				DialectGo:         `(?-s:a.|(?s:b.))`,
+				DialectPCRE:       `a[^\n]|b(?s:.)`,
+				DialectJavaScript: `a[^\n]|b[\s\S]`,
+				DialectPython:     `a.|b(?s:.)`,
+				DialectJava:       `a[^\n]|b(?s:.)`,
+				DialectERE:        "a[^\n]|b.",
+			},
+		},
+		{
+			name:     "text anchors",
+			patterns: []string{`\Aabc\z`, `\Aabd\z`},
+			expected: map[Dialect]string{
+				DialectGo:         `\Aab[cd]\z`,
+				DialectPCRE:       `\Aab[cd]\z`,
+				DialectJavaScript: `^ab[cd]$`,
+				DialectPython:     `\Aab[cd]\Z`,
+				DialectJava:       `\Aab[cd]\z`,
+				DialectERE:        `^ab[cd]$`,
+			},
+		},
+		{
+			name:     "line anchors",
+			patterns: []string{`^abc$`, `^abd$`},
+			expected: map[Dialect]string{
+				DialectGo:         `(?m:^ab[cd]$)`,
+				DialectPCRE:       `(?<![^\n])ab[cd](?m:$)`,
+				DialectJavaScript: `(?<![^\n])ab[cd](?=\n|$)`,
+				DialectPython:     `(?m:^)ab[cd](?m:$)`,
+				DialectJava:       `(?<![^\n])ab[cd](?![^\n])`,
+				DialectERE:        "",
+			},
+		},
+		{
+			name:     "word boundaries",
+			patterns: []string{`\babc\B`, `\babd\B`},
+			expected: map[Dialect]string{
+				DialectGo:         `\bab[cd]\B`,
+				DialectPCRE:       `\bab[cd]\B`,
+				DialectJavaScript: `\bab[cd]\B`,
+				DialectPython:     `\bab[cd]\B`,
+				DialectJava:       `\bab[cd]\B`,
+				DialectERE:        "",
+			},
+		},
+		{
+			name:     "case insensitive literals",
+			patterns: []string{`x(?i:abc)`, `x(?i:d)`},
+			expected: map[Dialect]string{
+				DialectGo:         `x(?i:ABC|D)`,
+				DialectPCRE:       `x(?:(?i:ABC)|(?i:D))`,
+				DialectJavaScript: "",
+				DialectPython:     `x(?:(?i:ABC)|(?i:D))`,
+				DialectJava:       `x(?:(?iu:ABC)|(?iu:D))`,
+				DialectERE:        "",
+			},
+		},
+		{
+			name:     "case insensitive non-letters",
+			patterns: []string{`(?i:1)`, `(?i:2)3`},
+			expected: map[Dialect]string{
+				DialectGo:         `1|23`,
+				DialectPCRE:       `1|23`,
+				DialectJavaScript: `1|23`,
+				DialectPython:     `1|23`,
+				DialectJava:       `1|23`,
+				DialectERE:        `1|23`,
+			},
+		},
+		{
+			name:     "captures",
+			patterns: []string{`(a)`, `(?P<name>b)`, `()`},
+			expected: map[Dialect]string{
+				DialectGo:         `(a)|(?P<name>b)|()`,
+				DialectPCRE:       `(a)|(?<name>b)|()`,
+				DialectJavaScript: `(a)|(?<name>b)|()`,
+				DialectPython:     `(a)|(?P<name>b)|()`,
+				DialectJava:       `(a)|(?<name>b)|()`,
+				DialectERE:        "",
+			},
+		},
+		{
+			name:     "capture name unsupported in java",
+			patterns: []string{`(?P<a_1>a)`},
+			expected: map[Dialect]string{
+				DialectGo:         `(?P<a_1>a)`,
+				DialectPCRE:       `(?<a_1>a)`,
+				DialectJavaScript: `(?<a_1>a)`,
+				DialectPython:     `(?P<a_1>a)`,
+				DialectJava:       "",
+				DialectERE:        "",
+			},
+		},
+		{
+			name:     "repetitions",
+			patterns: []string{`a*b+c?`, `(?:ab){2}`, `[ab]{2,}`, `(?:)+x{2,3}`},
+			expected: map[Dialect]string{
+				DialectGo:         `a*b+c?|(?:ab){2}|[ab]{2,}|(?:)+x{2,3}`,
+				DialectPCRE:       `a*b+c?|(?:ab){2}|[ab]{2,}|(?:)+x{2,3}`,
+				DialectJavaScript: `a*b+c?|(?:ab){2}|[ab]{2,}|(?:)+x{2,3}`,
+				DialectPython:     `a*b+c?|(?:ab){2}|[ab]{2,}|(?:)+x{2,3}`,
+				DialectJava:       `a*b+c?|(?:ab){2}|[ab]{2,}|(?:)+x{2,3}`,
+				DialectERE:        `a*b+c?|(ab){2}|[ab]{2,}|()+x{2,3}`,
+			},
+		},
+		{
+			name:     "non-greedy repetitions",
+			patterns: []string{`a*?b+?c??d{2,3}?`},
+			expected: map[Dialect]string{
+				DialectGo:         `a*?b+?c??d{2,3}?`,
+				DialectPCRE:       `a*?b+?c??d{2,3}?`,
+				DialectJavaScript: `a*?b+?c??d{2,3}?`,
+				DialectPython:     `a*?b+?c??d{2,3}?`,
+				DialectJava:       `a*?b+?c??d{2,3}?`,
+				DialectERE:        "",
+			},
+		},
+		{
+			name:     "repeated assertions",
+			patterns: []string{`foo$`, `foo`, `\bbar`, `bar`, `^{1,2}x`, `\Az?`},
+			expected: map[Dialect]string{
+				DialectGo:         `(?m:foo$?|\b?bar|^{1,2}x|\Az?)`,
+				DialectPCRE:       `foo(?:(?m:$))?|(?:\b)?bar|(?:(?<![^\n])){1,2}x|\Az?`,
+				DialectJavaScript: `foo(?:(?=\n|$))?|(?:\b)?bar|(?:(?<![^\n])){1,2}x|^z?`,
+				DialectPython:     `foo(?:(?m:$))?|(?:\b)?bar|(?:(?m:^)){1,2}x|\Az?`,
+				DialectJava:       `foo(?:(?![^\n]))?|(?:\b)?bar|(?:(?<![^\n])){1,2}x|\Az?`,
+				DialectERE:        "",
+			},
+		},
+		{
+			name:     "repeated text anchors",
+			patterns: []string{`\Aa`, `a`, `a\z`},
+			expected: map[Dialect]string{
+				DialectGo:         `\Aa|a\z?`,
+				DialectPCRE:       `\Aa|a(?:\z)?`,
+				DialectJavaScript: `^a|a(?:$)?`,
+				DialectPython:     `\Aa|a(?:\Z)?`,
+				DialectJava:       `\Aa|a(?:\z)?`,
+				DialectERE:        `^a|a($)?`,
+			},
+		},
+		{
+			name:     "empty literal",
+			patterns: []string{``},
+			expected: map[Dialect]string{
+				DialectGo:         `(?:)`,
+				DialectPCRE:       `(?:)`,
+				DialectJavaScript: `(?:)`,
+				DialectPython:     `(?:)`,
+				DialectJava:       `(?:)`,
+				DialectERE:        `()`,
+			},
+		},
+		{
+			name:     "no match",
+			patterns: []string{`[^\x00-\x{10FFFF}]`},
+			expected: map[Dialect]string{
+				DialectGo:         `[^\x00-\x{10FFFF}]`,
+				DialectPCRE:       `(?!)`,
+				DialectJavaScript: `(?!)`,
+				DialectPython:     `(?!)`,
+				DialectJava:       `(?!)`,
+				DialectERE:        "",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := JoinSyntax(tc.patterns)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			for d := DialectGo; d <= DialectERE; d++ {
+				expected := tc.expected[d]
+				got, err := Render(r, d)
+				if expected == "" {
+					if err == nil {
+						t.Errorf("%s: expected an error but got: %s", d, got)
+					}
+					continue
+				}
+				if err != nil {
+					t.Errorf("%s: got an error: %s", d, err)
+				} else if got != expected {
+					t.Errorf("%s: expected: %s, got: %s", d, expected, got)
+				} else if err := compileDialect(d, got); err != nil {
+					t.Errorf("%s: %s: %s", d, got, err)
+				}
+			}
+		})
+	}
+	if _, err := Render(nil, -1); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestRenderPCRE(t *testing.T) {
+	// PCRE output is also valid in Go regexp except the lookbehind for the
+	// beginning of line, so it can be compared.
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := JoinSyntax(tc.patterns)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			s, err := Render(r, DialectPCRE)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			s = strings.ReplaceAll(s, `(?<![^\n])`, `(?m:^)`)
+			re1, re2 := regexp.MustCompile(r.String()), regexp.MustCompile(s)
+			for _, p := range tc.patterns {
+				for _, input := range []string{p, p + p, "\n" + p + "\n", "A" + p} {
+					if got, expected := re2.FindStringIndex(input), re1.FindStringIndex(input); !slices.Equal(got, expected) {
+						t.Errorf("%s: %q: expected: %v, got: %v", s, input, expected, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderShorthand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		expected map[Dialect]string
+	}{
+		{
+			name:     "digits",
+			patterns: []string{`x\d`, `x[a-f]`, `[0-9]`},
+			expected: map[Dialect]string{
+				DialectGo:         `x[\da-f]|\d`,
+				DialectPCRE:       `x[\da-f]|\d`,
+				DialectJavaScript: `x[\da-f]|\d`,
+				DialectPython:     `x[0-9a-f]|[0-9]`,
+				DialectJava:       `x[\da-f]|\d`,
+				DialectERE:        `x[0-9a-f]|[0-9]`,
+			},
+		},
+		{
+			name:     "negated classes",
+			patterns: []string{`x[^0-9]`, `y[^\w-]`, `z\S`},
+			expected: map[Dialect]string{
+				DialectGo:         `x\D|y[^\w\-]|z\S`,
+				DialectPCRE:       `x\D|y[^\w\-]|z[^\t\n\f\r ]`,
+				DialectJavaScript: `x\D|y[^\w\-]|z[^\t\n\f\r ]`,
+				DialectPython:     `x[^0-9]|y[^\-0-9A-Z_a-z]|z[^\t\n\f\r ]`,
+				DialectJava:       `x\D|y[^\w\-]|z[^\t\n\f\r ]`,
+				DialectERE:        "x[^0-9]|y[^0-9A-Z_a-z-]|z[^\t\n\f\r ]",
+			},
+		},
+		{
+			name:     "dots",
+			patterns: []string{`a.`, `b[^\n]`, `c[^0-9]`},
+			expected: map[Dialect]string{
+				DialectGo:         `[ab].|c\D`,
+				DialectPCRE:       `[ab][^\n]|c\D`,
+				DialectJavaScript: `[ab][^\n]|c\D`,
+				DialectPython:     `[ab].|c[^0-9]`,
+				DialectJava:       `[ab][^\n]|c\D`,
+				DialectERE:        "[ab][^\n]|c[^0-9]",
+			},
+		},
+		{
+			name:     "unicode classes",
+			patterns: []string{`x\p{Lu}`, `x\p{Ll}`, `\p{Greek}`, `[0-9]`, `y\PN`},
+			expected: map[Dialect]string{
+				DialectGo: `x[\p{Ll}\p{Lu}]|[\p{Greek}\d]|y\PN`,
+			},
+		},
+		{
+			name:     "no match",
+			patterns: []string{`[^\x00-\x{10FFFF}]`},
+			expected: map[Dialect]string{
+				DialectGo:   `[^\x00-\x{10FFFF}]`,
+				DialectPCRE: `(?!)`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAssembler(Options{Flags: DefaultFlags | syntax.UnicodeGroups})
+			for _, p := range tc.patterns {
+				if err := a.Add(p); err != nil {
+					t.Fatalf("got an error: %s", err)
+				}
+			}
+			r := a.Regexp()
+			for d, expected := range tc.expected {
+				got, err := RenderShorthand(r, d)
+				if err != nil {
+					t.Errorf("%s: got an error: %s", d, err)
+				} else if got != expected {
+					t.Errorf("%s: expected: %s, got: %s", d, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderShorthandGo(t *testing.T) {
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := JoinSyntax(tc.patterns)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			s, err := RenderShorthand(r, DialectGo)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			re1, re2 := regexp.MustCompile(r.String()), regexp.MustCompile(s)
+			for _, p := range tc.patterns {
+				for _, input := range []string{p, p + p, "\n" + p + "\n", "A" + p} {
+					if got, expected := re2.FindStringIndex(input), re1.FindStringIndex(input); !slices.Equal(got, expected) {
+						t.Errorf("%s: %q: expected: %v, got: %v", s, input, expected, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPCREBeginLine(t *testing.T) {
+	r, err := JoinSyntax([]string{`(?m:^)\z`})
+	if err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	s, err := Render(r, DialectPCRE)
+	if err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	if !regexp.MustCompile(r.String()).MatchString("a\n") {
+		t.Fatalf("expected %s to match", r)
+	}
+	// (?m:^) does not match after the final newline in Perl
+	cmd := exec.Command("perl", "-e", `exit("a\n" =~ qr/$ARGV[0]/ ? 0 : 1)`, s)
+	if cmd.Err != nil {
+		t.Skip(cmd.Err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Errorf("expected %s to match in Perl: %s", s, err)
+	}
+}
+
+func TestRenderJavaScriptEndLine(t *testing.T) {
+	r, err := JoinSyntax([]string{`a*$`})
+	if err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	s, err := Render(r, DialectJavaScript)
+	if err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	// (?![^\n]) matches between the surrogates of 😀 in V8
+	cmd := exec.Command("node", "-e",
+		`process.exit(new RegExp(process.argv[1], "u").exec("x😀b").index === 4 ? 0 : 1)`, s)
+	if cmd.Err != nil {
+		t.Skip(cmd.Err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Errorf("expected %s to match at the end in JavaScript: %s", s, err)
+	}
+}
+
+func TestRenderEREClass(t *testing.T) {
+	testCases := []struct {
+		runes    []rune
+		expected string
+	}{
+		{[]rune{'-', '-', '^', '^'}, `[-^]`},
+		{[]rune{'^', '^'}, `\^`},
+		{[]rune{']', ']', '^', '^'}, `[]^]`},
+		{[]rune{0, ',', '.', ']', '_', unicode.MaxRune}, `[^^-]`},
+		{[]rune{0, '\t', '\v', unicode.MaxRune}, "[^\n]"},
+	}
+	for _, tc := range testCases {
+		got, err := Render(&syntax.Regexp{Op: syntax.OpCharClass, Rune: tc.runes}, DialectERE)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if got != tc.expected {
+			t.Errorf("expected: %s, got: %s", tc.expected, got)
+		}
+	}
+}
+
+func TestTrimClass(t *testing.T) {
+	testCases := []struct {
+		rs, covered []rune
+		expected    string
+	}{
+		{[]rune{'a', 'z'}, []rune{'a', 'a', 'e', 'e', 'i', 'i', 'o', 'o', 'u', 'u'}, "[b z]"},
+		{[]rune{'0', '9', 'a', 'f'}, []rune{'0', '9'}, "[a f]"},
+		{[]rune{'0', '9'}, []rune{'0', '9'}, "[]"},
+	}
+	for _, tc := range testCases {
+		if got := fmt.Sprintf("%c", trimClass(tc.rs, tc.covered)); got != tc.expected {
+			t.Errorf("expected: %s, got: %s", tc.expected, got)
+		}
+	}
+}
+
+// compileDialect compiles the pattern with the engine of the dialect.
+// The dialects without the engine installed are skipped.
+func compileDialect(d Dialect, s string) error {
+	var cmd *exec.Cmd
+	switch d {
+	case DialectGo:
+		_, err := regexp.Compile(s)
+		return err
+	case DialectPCRE:
+		cmd = exec.Command("perl", "-e", `qr/$ARGV[0]/`, s)
+	case DialectJavaScript:
+		cmd = exec.Command("node", "-e", `new RegExp(process.argv[1], "u")`, s)
+	case DialectPython:
+		cmd = exec.Command("python3", "-c", "import re, sys; re.compile(sys.argv[1])", s)
+	case DialectERE:
+		if strings.ContainsAny(s, "\x00\n") {
+			return nil // grep takes the patterns line by line
+		}
+		cmd = exec.Command("grep", "-E", "-e", s)
+	default:
+		return nil
+	}
+	if cmd.Err != nil {
+		return nil
+	}
+	out, err := cmd.CombinedOutput()
+	if err, ok := err.(*exec.ExitError); ok && d == DialectERE && err.ExitCode() == 1 {
+		return nil // no match in the empty input
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, out)
+	}
+	return nil
+}
+
+func TestDialectText(t *testing.T) {
+	for d := DialectGo; d <= DialectERE; d++ {
+		text, err := d.MarshalText()
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		var got Dialect
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if got != d {
+			t.Errorf("expected: %s, got: %s", d, got)
+		}
+	}
+	var d Dialect
+	if err := d.UnmarshalText([]byte("PCRE")); err != nil || d != DialectPCRE {
+		t.Errorf("expected: %s, got: %s", DialectPCRE, d)
+	}
+	if err := d.UnmarshalText([]byte("unknown")); err == nil {
+		t.Errorf("expected an error")
+	}
+	if got, expected := Dialect(-1).String(), "Dialect(-1)"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
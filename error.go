@@ -0,0 +1,22 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"strconv"
+)
+
+// PatternError is an error on parsing a pattern.
+type PatternError struct {
+	Index   int           // index of the pattern in the order of addition
+	Pattern string        // the pattern
+	Err     *syntax.Error // error from the parser
+}
+
+func (err *PatternError) Error() string {
+	return "pattern " + strconv.Itoa(err.Index) + ": " + err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *PatternError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,57 @@
+package rassemble
+
+import (
+	"errors"
+	"regexp/syntax"
+	"testing"
+)
+
+func TestPatternError(t *testing.T) {
+	patterns := []string{"a", "*", "b", "(c", "d"}
+	_, err := Join(patterns)
+	var perr *PatternError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a *PatternError but got: %#v", err)
+	}
+	if perr.Index != 1 || perr.Pattern != "*" || perr.Err.Code != syntax.ErrMissingRepeatArgument {
+		t.Errorf("unexpected error: %#v", perr)
+	}
+	if got, expected := err.Error(),
+		"pattern 1: error parsing regexp: missing argument to repetition operator: `*`"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+	var serr *syntax.Error
+	if !errors.As(err, &serr) || serr != perr.Err {
+		t.Errorf("expected to unwrap *syntax.Error: %#v", err)
+	}
+
+	_, err = JoinWithOptions(patterns, Options{AllErrors: true})
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if got, expected := len(errs), 2; got != expected {
+		t.Fatalf("expected: %d, got: %d", expected, got)
+	}
+	for i, expected := range []struct {
+		index   int
+		pattern string
+		code    syntax.ErrorCode
+	}{
+		{1, "*", syntax.ErrMissingRepeatArgument},
+		{3, "(c", syntax.ErrMissingParen},
+	} {
+		if !errors.As(errs[i], &perr) {
+			t.Fatalf("expected a *PatternError but got: %#v", errs[i])
+		}
+		if perr.Index != expected.index || perr.Pattern != expected.pattern || perr.Err.Code != expected.code {
+			t.Errorf("unexpected error: %#v", perr)
+		}
+	}
+
+	var a Assembler
+	for i, pattern := range patterns {
+		if err := a.Add(pattern); err != nil {
+			if !errors.As(err, &perr) || perr.Index != i {
+				t.Errorf("unexpected error: %#v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package rassemble
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/rand/v2"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func FuzzJoin(f *testing.F) {
+	for _, tc := range joinTestCases {
+		f.Add(strings.Join(tc.patterns, "\n"))
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		patterns := strings.Split(s, "\n")
+		if len(patterns) > 16 || len(s) > 256 {
+			t.Skip()
+		}
+		rs := make([]*syntax.Regexp, len(patterns))
+		for i, pattern := range patterns {
+			r, err := syntax.Parse(pattern, DefaultFlags)
+			if err != nil {
+				t.Skip()
+			}
+			rs[i] = r
+		}
+		r, err := JoinSyntax(patterns)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		h := fnv.New64()
+		h.Write([]byte(s))
+		checkMatches(t, rs, r, rand.New(rand.NewPCG(h.Sum64(), 0)))
+		if err := Verify(patterns, r.String()); err != nil && !errors.Is(err, ErrTooManyStates) {
+			t.Fatalf("assembled: %s: %s", r, err)
+		}
+	})
+}
+
+func TestJoinRandom(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		patterns := make([]string, 1+rnd.IntN(5))
+		rs := make([]*syntax.Regexp, len(patterns))
+		for i := range patterns {
+			patterns[i] = randomPattern(rnd, 3)
+			r, err := syntax.Parse(patterns[i], DefaultFlags)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			rs[i] = r
+		}
+		r, err := JoinSyntax(patterns)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if !checkMatches(t, rs, r, rnd) {
+			t.Fatalf("patterns: %q, assembled: %s", patterns, r)
+		}
+		if err := Verify(patterns, r.String()); err != nil && !errors.Is(err, ErrTooManyStates) {
+			t.Fatalf("patterns: %q, assembled: %s: %s", patterns, r, err)
+		}
+	}
+}
+
+func TestJoinLeftmostFirst(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	opts := Options{LeftmostFirst: true}
+	check := func(patterns []string) {
+		t.Helper()
+		rs := make([]*syntax.Regexp, len(patterns))
+		for i, pattern := range patterns {
+			r, err := syntax.Parse(pattern, DefaultFlags)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			rs[i] = r
+		}
+		got, err := JoinWithOptions(patterns, opts)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if err := Verify(patterns, got); err != nil && !errors.Is(err, ErrTooManyStates) {
+			t.Fatalf("patterns: %q, assembled: %s: %s", patterns, got, err)
+		}
+		expected := regexp.MustCompile(
+			(&syntax.Regexp{Op: syntax.OpAlternate, Sub: rs}).String(),
+		)
+		re := regexp.MustCompile(got)
+		for _, r := range rs {
+			for range 3 {
+				var sb strings.Builder
+				if !generate(&sb, r, rnd) {
+					continue
+				}
+				s := sb.String()
+				for _, s := range []string{s, s + s, s[:len(s)/2], s + "a", "a" + s + "\n"} {
+					if x, y := expected.FindStringIndex(s), re.FindStringIndex(s); !slices.Equal(x, y) {
+						t.Fatalf("patterns: %q, assembled: %s: %q: expected: %v, got: %v",
+							patterns, got, s, x, y)
+					}
+				}
+			}
+		}
+	}
+	for _, tc := range joinTestCases {
+		if len(tc.patterns) > 0 {
+			check(tc.patterns)
+		}
+	}
+	for range 1000 {
+		patterns := make([]string, 1+rnd.IntN(5))
+		for i := range patterns {
+			patterns[i] = randomPattern(rnd, 3)
+		}
+		check(patterns)
+	}
+}
+
+func TestJoinPreferLongest(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	opts := Options{Literal: true, PreferLongest: true}
+	word := func() string {
+		var sb strings.Builder
+		for range rnd.IntN(5) {
+			sb.WriteByte("abc"[rnd.IntN(3)])
+		}
+		return sb.String()
+	}
+	for range 1000 {
+		words := make([]string, 1+rnd.IntN(8))
+		for i := range words {
+			words[i] = word()
+		}
+		got, err := JoinWithOptions(words, opts)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		expected := regexp.MustCompile(strings.Join(words, "|"))
+		expected.Longest()
+		re := regexp.MustCompile(got)
+		for range 10 {
+			s := word() + word()
+			if x, y := expected.FindStringIndex(s), re.FindStringIndex(s); !slices.Equal(x, y) {
+				t.Fatalf("words: %q, assembled: %s: %q: expected: %v, got: %v",
+					words, got, s, x, y)
+			}
+		}
+	}
+}
+
+// checkMatches checks that the assembled regular expression matches the
+// strings generated from the patterns if and only if any of them matches.
+func checkMatches(t *testing.T, rs []*syntax.Regexp, r *syntax.Regexp, rnd *rand.Rand) bool {
+	t.Helper()
+	anchored := func(r *syntax.Regexp) *regexp.Regexp {
+		if r.Op == syntax.OpAlternate && len(r.Sub) == 0 {
+			r = &syntax.Regexp{Op: syntax.OpNoMatch}
+		}
+		return regexp.MustCompile(concat(
+			&syntax.Regexp{Op: syntax.OpBeginText}, r, &syntax.Regexp{Op: syntax.OpEndText},
+		).String())
+	}
+	res := make([]*regexp.Regexp, len(rs))
+	for i, r := range rs {
+		res[i] = anchored(r)
+	}
+	re := anchored(r)
+	inputs := []string{""}
+	for _, r := range rs {
+		for range 3 {
+			var sb strings.Builder
+			if generate(&sb, r, rnd) {
+				s := sb.String()
+				inputs = append(inputs, s, s+s, s[:len(s)/2], s+"a", "a"+s)
+			}
+		}
+	}
+	for _, s := range inputs {
+		var expected bool
+		for _, re := range res {
+			if re.MatchString(s) {
+				expected = true
+				break
+			}
+		}
+		if got := re.MatchString(s); got != expected {
+			t.Errorf("%s: %q: expected: %t, got: %t", r, s, expected, got)
+			return false
+		}
+	}
+	return true
+}
+
+// generate writes a random string the regular expression likely matches,
+// ignoring the assertions. It reports false if nothing can be generated.
+func generate(sb *strings.Builder, r *syntax.Regexp, rnd *rand.Rand) bool {
+	if sb.Len() > 1000 {
+		return true
+	}
+	switch r.Op {
+	case syntax.OpNoMatch:
+		return false
+	case syntax.OpLiteral:
+		for _, c := range r.Rune {
+			if r.Flags&syntax.FoldCase != 0 {
+				for range rnd.IntN(3) {
+					c = unicode.SimpleFold(c)
+				}
+			}
+			sb.WriteRune(c)
+		}
+	case syntax.OpCharClass:
+		if len(r.Rune) == 0 {
+			return false
+		}
+		i := rnd.IntN(len(r.Rune)/2) * 2
+		lo, hi := r.Rune[i], min(r.Rune[i+1], r.Rune[i]+0x100)
+		sb.WriteRune(lo + rnd.Int32N(hi-lo+1))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		sb.WriteRune([]rune("ab\n!")[rnd.IntN(4)])
+	case syntax.OpCapture:
+		return generate(sb, r.Sub[0], rnd)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		lo, hi := 0, 1
+		switch r.Op {
+		case syntax.OpStar:
+			hi = 2
+		case syntax.OpPlus:
+			lo, hi = 1, 2
+		case syntax.OpRepeat:
+			lo, hi = r.Min, r.Max
+			if hi < 0 {
+				hi = lo + 2
+			}
+		}
+		for range lo + rnd.IntN(min(hi-lo, 3)+1) {
+			if !generate(sb, r.Sub[0], rnd) {
+				return false
+			}
+		}
+	case syntax.OpConcat:
+		for _, r := range r.Sub {
+			if !generate(sb, r, rnd) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		for _, i := range rnd.Perm(len(r.Sub)) {
+			var s strings.Builder
+			if generate(&s, r.Sub[i], rnd) {
+				sb.WriteString(s.String())
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// randomPattern generates a random pattern of the depth.
+func randomPattern(rnd *rand.Rand, depth int) string {
+	atoms := []string{"a", "b", "c", "ab", "ba", "[ab]", "[^a]", "[^ab]", `\W`, `\D`, `\S`, ".", "", `\b`, "^", "$", "(?i:a)"}
+	if depth == 0 {
+		return atoms[rnd.IntN(len(atoms))]
+	}
+	switch x := randomPattern(rnd, depth-1); rnd.IntN(8) {
+	case 0:
+		return "(?:" + x + ")?"
+	case 1:
+		return "(?:" + x + ")*"
+	case 2:
+		return "(?:" + x + ")+"
+	case 3:
+		return "(?:" + x + "){1,2}"
+	case 4:
+		return x + "|" + randomPattern(rnd, depth-1)
+	default:
+		return "(?:" + x + ")" + randomPattern(rnd, depth-1)
+	}
+}
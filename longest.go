@@ -0,0 +1,93 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"slices"
+)
+
+// preferLongest orders the alternatives by their lengths in descending order
+// and makes the repetitions greedy, so that the backtracking engines try the
+// longer strings first.
+//
+//	a|bc|b*d => b*d|bc|a
+//	(?:ab)?? => (?:ab)?
+func preferLongest(r *syntax.Regexp) *syntax.Regexp {
+	for _, rr := range r.Sub {
+		preferLongest(rr)
+	}
+	switch r.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		r.Flags &^= syntax.NonGreedy
+	case syntax.OpAlternate:
+		// the alternatives matching the empty string are tried last
+		slices.SortStableFunc(r.Sub, func(r1, r2 *syntax.Regexp) int {
+			min1, max1 := lengthRange(r1)
+			min2, max2 := lengthRange(r2)
+			switch {
+			case (min1 == 0) != (min2 == 0):
+				return min2 - min1
+			case max1 == max2:
+				return min2 - min1
+			case max1 < 0 || max2 >= 0 && max1 > max2:
+				return -1
+			default:
+				return 1
+			}
+		})
+	}
+	return r
+}
+
+// lengthRange returns the minimum and maximum numbers of the characters the
+// pattern matches, where the maximum is -1 if unbounded.
+func lengthRange(r *syntax.Regexp) (int, int) {
+	switch r.Op {
+	case syntax.OpLiteral:
+		return len(r.Rune), len(r.Rune)
+	case syntax.OpCharClass, syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		return 1, 1
+	case syntax.OpCapture:
+		return lengthRange(r.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		lo, hi := lengthRange(r.Sub[0])
+		switch r.Op {
+		case syntax.OpStar:
+			lo = 0
+			fallthrough
+		case syntax.OpPlus:
+			if hi != 0 {
+				hi = -1
+			}
+		case syntax.OpQuest:
+			lo = 0
+		default:
+			lo *= r.Min
+			if hi > 0 && r.Max < 0 {
+				hi = -1
+			} else if hi > 0 {
+				hi *= r.Max
+			}
+		}
+		return lo, hi
+	case syntax.OpConcat:
+		var lo, hi int
+		for _, rr := range r.Sub {
+			l, h := lengthRange(rr)
+			lo, hi = sumRange(lo, hi, l, h)
+		}
+		return lo, hi
+	case syntax.OpAlternate:
+		var lo, hi int
+		for i, rr := range r.Sub {
+			l, h := lengthRange(rr)
+			if i == 0 || l < lo {
+				lo = l
+			}
+			if i == 0 || hi >= 0 && (h < 0 || h > hi) {
+				hi = h
+			}
+		}
+		return lo, hi
+	}
+	return 0, 0
+}
@@ -0,0 +1,36 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"testing"
+)
+
+func TestLengthRange(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		lo, hi  int
+	}{
+		{"abc", 3, 3},
+		{"[ab].", 2, 2},
+		{"(ab)", 2, 2},
+		{"a*", 0, -1},
+		{"(?:)+", 0, 0},
+		{"a?", 0, 1},
+		{"a{2,3}", 2, 3},
+		{"(?:ab){2,}", 4, -1},
+		{"a{0}", 0, 0},
+		{"(?:a*){2}", 0, -1},
+		{"a|bc", 1, 2},
+		{"ab|c*", 0, -1},
+		{`\bab$`, 2, 2},
+	}
+	for _, tc := range testCases {
+		r, err := syntax.Parse(tc.pattern, DefaultFlags)
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if lo, hi := lengthRange(r); lo != tc.lo || hi != tc.hi {
+			t.Errorf("%s: expected: %d, %d, got: %d, %d", tc.pattern, tc.lo, tc.hi, lo, hi)
+		}
+	}
+}
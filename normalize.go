@@ -0,0 +1,174 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"unicode"
+)
+
+// normalizeAssertions removes the redundant zero-width assertions and sorts
+// the successive ones, so that they are merged across the patterns.
+func normalizeAssertions(r *syntax.Regexp) *syntax.Regexp {
+	for i, rr := range r.Sub {
+		r.Sub[i] = normalizeAssertions(rr)
+	}
+	switch r.Op {
+	case syntax.OpEndText:
+		// $ without the multi-line flag is the same as \z
+		r.Flags &^= syntax.WasDollar
+	case syntax.OpConcat:
+		return concat(normalizeRuns(r.Sub)...)
+	}
+	return r
+}
+
+// normalizeRuns sorts the successive assertions and removes the redundant ones,
+// and the empty matches.
+//
+//	\b(?m:^)\A\b => \A\b
+func normalizeRuns(rs []*syntax.Regexp) []*syntax.Regexp {
+	sub := make([]*syntax.Regexp, 0, len(rs))
+	for i := 0; i < len(rs); {
+		j := i
+		for j < len(rs) && isAssertion(rs[j].Op) {
+			j++
+		}
+		if i == j {
+			if rs[i].Op != syntax.OpEmptyMatch {
+				sub = append(sub, rs[i])
+			}
+			i++
+			continue
+		}
+		run := rs[i:j]
+		sort.SliceStable(run, func(i, j int) bool {
+			return run[i].Op < run[j].Op
+		})
+		for k, r := range run {
+			if k > 0 && run[k-1].Op == r.Op || implied(run, r.Op) {
+				continue
+			}
+			sub = append(sub, r)
+		}
+		i = j
+	}
+	return sub
+}
+
+func isAssertion(op syntax.Op) bool {
+	return syntax.OpBeginLine <= op && op <= syntax.OpNoWordBoundary
+}
+
+// implies reports whether the assertion implies the other one.
+func implies(op1, op2 syntax.Op) bool {
+	return op1 == op2 ||
+		op1 == syntax.OpBeginText && op2 == syntax.OpBeginLine ||
+		op1 == syntax.OpEndText && op2 == syntax.OpEndLine
+}
+
+func implied(rs []*syntax.Regexp, op syntax.Op) bool {
+	for _, r := range rs {
+		if r.Op != op && implies(r.Op, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimAssertion removes the leading (or trailing) assertions implied by the
+// assertion, without modifying the argument.
+func trimAssertion(r *syntax.Regexp, op syntax.Op, leading bool) *syntax.Regexp {
+	if implies(op, r.Op) {
+		return &syntax.Regexp{Op: syntax.OpEmptyMatch}
+	}
+	switch r.Op {
+	case syntax.OpConcat:
+		sub := append([]*syntax.Regexp(nil), r.Sub...)
+		i := 0
+		if !leading {
+			i = len(sub) - 1
+		}
+		if sub[i] = trimAssertion(sub[i], op, leading); sub[i].Op == syntax.OpEmptyMatch {
+			sub = append(sub[:i], sub[i+1:]...)
+		}
+		return concat(sub...)
+	case syntax.OpAlternate, syntax.OpCapture:
+		rr, empty := *r, r.Op == syntax.OpAlternate
+		rr.Sub = make([]*syntax.Regexp, len(r.Sub))
+		for i, r := range r.Sub {
+			rr.Sub[i] = trimAssertion(r, op, leading)
+			empty = empty && rr.Sub[i].Op == syntax.OpEmptyMatch
+		}
+		if empty {
+			// (?:^|\A) => (?:)
+			return rr.Sub[0]
+		}
+		return &rr
+	default:
+		return r
+	}
+}
+
+// normalizeFlags clears the flags irrelevant to each node, so that the nodes
+// with the same meaning are equal regardless of the flags of the patterns.
+//
+//	(?i:1) => 1, (?s:a) => a
+func normalizeFlags(r *syntax.Regexp) *syntax.Regexp {
+	for _, rr := range r.Sub {
+		normalizeFlags(rr)
+	}
+	switch r.Op {
+	case syntax.OpLiteral:
+		if r.Flags&syntax.FoldCase != 0 && isFoldSensitive(r.Rune) {
+			r.Flags = syntax.FoldCase
+		} else {
+			r.Flags = 0
+		}
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		r.Flags &= syntax.NonGreedy
+	default:
+		// the case folding of the character classes is already applied
+		r.Flags = 0
+	}
+	return r
+}
+
+// expandAnyChar expands the dots into the classes,
+// so that they can be merged with the other classes.
+//
+//	(?-s:.) => [^\n], (?s:.) => [\x00-\x{10FFFF}]
+func expandAnyChar(r *syntax.Regexp) *syntax.Regexp {
+	for _, rr := range r.Sub {
+		expandAnyChar(rr)
+	}
+	switch r.Op {
+	case syntax.OpAnyCharNotNL:
+		r.Op, r.Rune = syntax.OpCharClass, []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}
+	case syntax.OpAnyChar:
+		r.Op, r.Rune = syntax.OpCharClass, []rune{0, unicode.MaxRune}
+	}
+	return r
+}
+
+// foldAnyChar folds the classes back into the dots.
+//
+//	[^\n] => (?-s:.)
+//	[^a]|a => (?s:.)
+func foldAnyChar(r *syntax.Regexp) *syntax.Regexp {
+	for _, rr := range r.Sub {
+		foldAnyChar(rr)
+	}
+	if r.Op == syntax.OpCharClass {
+		switch rs := normalizeClass(r.Rune); {
+		case slices.Equal(rs, []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}):
+			r.Op, r.Rune = syntax.OpAnyCharNotNL, nil
+		case slices.Equal(rs, []rune{0, unicode.MaxRune}):
+			r.Op, r.Rune = syntax.OpAnyChar, nil
+		case len(rs) > 0 && rs[0] == 0 && rs[len(rs)-1] == unicode.MaxRune:
+			// String prints the negated class assuming the normalized ranges
+			r.Rune = rs
+		}
+	}
+	return r
+}
@@ -0,0 +1,168 @@
+package rassemble
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// DefaultFlags is the flags to parse the patterns in Join.
+const DefaultFlags = syntax.PerlX | syntax.ClassNL
+
+// Options is the options for JoinWithOptions and NewAssembler.
+type Options struct {
+	// Flags is the flags to parse the patterns. The zero value means
+	// DefaultFlags, the same as Join, unless POSIX is set.
+	Flags syntax.Flags
+	// POSIX parses the patterns with Flags even if it is zero, which is
+	// syntax.POSIX.
+	POSIX bool
+	// Literal treats the patterns as literal strings.
+	Literal bool
+	// AllErrors reports all the errors of the patterns joined by errors.Join,
+	// rather than stopping at the first one.
+	AllErrors bool
+	// FoldRepeats folds the successive repetitions of the same expression
+	// into a counted repetition like 0{6,8}, when the pattern gets shorter.
+	FoldRepeats bool
+	// MergeCase merges the classes of all the case variants of a string
+	// like [Ff][Oo][Oo] into a case-insensitive literal (?i:foo), when the
+	// pattern gets shorter. This has no effect with ExpandCase.
+	MergeCase bool
+	// ExpandCase expands the case-insensitive literals like (?i:foo) into
+	// the classes [Ff][Oo][Oo], for the dialects without inline flags.
+	ExpandCase bool
+	// Canonical makes the output independent of the order of the patterns,
+	// by assembling the alternatives sorted by their patterns. The patterns
+	// are reassembled when Regexp is called after adding or removing them.
+	// This has no effect with LeftmostFirst.
+	Canonical bool
+	// LeftmostFirst keeps the priority of the patterns, so that the assembled
+	// pattern finds the same match as trying the patterns in the order, like
+	// the alternation in Go (a|ab => ab??, not ab?). The patterns are merged
+	// less than the default, only where the priority is preserved.
+	LeftmostFirst bool
+	// PreferLongest orders the alternatives by their lengths, the longer first,
+	// and makes the repetitions greedy, so that the backtracking engines like
+	// PCRE find the longer alternative first (a|bc|b*d => b*d|bc|a).
+	// The merging of Join keeps the strings the pattern matches, so it is safe
+	// under the POSIX leftmost-longest semantics (see regexp.Regexp.Longest),
+	// and the factored prefixes followed by the greedy quests try the longer
+	// strings first. Under the Perl semantics, the order is only by the lengths
+	// of the alternatives, so a shorter match can still be found depending on
+	// what follows, like (?:ab|a)(?:bc)? on abc. This has no effect with
+	// LeftmostFirst.
+	PreferLongest bool
+	// Captures is the policy of the capture groups in the patterns.
+	Captures Captures
+	// Names is the names of the groups wrapping the patterns with
+	// CaptureInput. The same name can be used for multiple patterns.
+	// The default names are input0, input1, and so on.
+	Names []string
+	// Anchor anchors the assembled pattern.
+	Anchor Anchor
+	// Dialect is the syntax of the output pattern.
+	Dialect Dialect
+	// Shorthand spells the character classes with the shorthands like \d
+	// and \pL in the dialect, see RenderShorthand.
+	Shorthand bool
+}
+
+var defaultOptions = Options{Flags: DefaultFlags}
+
+// Anchor is the anchoring mode of the assembled pattern.
+type Anchor int
+
+// Anchoring modes.
+const (
+	AnchorNone Anchor = iota // no anchor
+	AnchorLine               // (?m:^...$)
+	AnchorText               // \A...\z
+	AnchorWord               // \b...\b
+)
+
+var anchorNames = [...]string{"none", "line", "text", "word"}
+
+func (a Anchor) String() string {
+	if 0 <= a && int(a) < len(anchorNames) {
+		return anchorNames[a]
+	}
+	return "Anchor(" + strconv.Itoa(int(a)) + ")"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Anchor) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Anchor) UnmarshalText(text []byte) error {
+	for i, name := range anchorNames {
+		if strings.EqualFold(string(text), name) {
+			*a = Anchor(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown anchor: %s", text)
+}
+
+func (a Anchor) wrap(r *syntax.Regexp) *syntax.Regexp {
+	var begin, end syntax.Op
+	switch a {
+	case AnchorLine:
+		begin, end = syntax.OpBeginLine, syntax.OpEndLine
+	case AnchorText:
+		begin, end = syntax.OpBeginText, syntax.OpEndText
+	case AnchorWord:
+		begin, end = syntax.OpWordBoundary, syntax.OpWordBoundary
+	default:
+		return r
+	}
+	// \A(?:\Afoo|(?m:^)bar) => \A(?:foo|bar)
+	r = trimAssertion(trimAssertion(r, begin, true), end, false)
+	r = flattenConcat(concat(&syntax.Regexp{Op: begin}, r, &syntax.Regexp{Op: end}))
+	return concat(normalizeRuns(r.Sub)...)
+}
+
+// Captures is the policy of the capture groups in the patterns. The capture
+// groups are numbered by the position of their opening parentheses in the
+// assembled pattern, so the indices differ from those in the patterns
+// (the first group of the second pattern is not \1 anymore, for example).
+// The names of the groups are kept, and the same names are allowed.
+type Captures int
+
+// Capture group policies.
+const (
+	CapturePreserve Captures = iota // keep the groups as they are
+	CaptureStrip                    // (a)b => ab
+	CaptureInput                    // ab => (?P<input0>ab), see Options.Names
+)
+
+func (c Captures) apply(r *syntax.Regexp, name string) *syntax.Regexp {
+	switch c {
+	case CaptureStrip:
+		return stripCaptures(r)
+	case CaptureInput:
+		return tag(r, name)
+	default:
+		return r
+	}
+}
+
+func stripCaptures(r *syntax.Regexp) *syntax.Regexp {
+	if r.Op == syntax.OpCapture {
+		return stripCaptures(r.Sub[0])
+	}
+	for i, rr := range r.Sub {
+		r.Sub[i] = stripCaptures(rr)
+	}
+	return r
+}
+
+func (opts *Options) name(id int) string {
+	if id < len(opts.Names) && opts.Names[id] != "" {
+		return opts.Names[id]
+	}
+	return "input" + strconv.Itoa(id)
+}
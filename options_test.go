@@ -0,0 +1,29 @@
+package rassemble
+
+import "testing"
+
+func TestAnchorText(t *testing.T) {
+	for a := AnchorNone; a <= AnchorWord; a++ {
+		text, err := a.MarshalText()
+		if err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		var got Anchor
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("got an error: %s", err)
+		}
+		if got != a {
+			t.Errorf("expected: %s, got: %s", a, got)
+		}
+	}
+	var a Anchor
+	if err := a.UnmarshalText([]byte("Text")); err != nil || a != AnchorText {
+		t.Errorf("expected: %s, got: %s", AnchorText, a)
+	}
+	if err := a.UnmarshalText([]byte("unknown")); err == nil {
+		t.Errorf("expected an error")
+	}
+	if got, expected := Anchor(-1).String(), "Anchor(-1)"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
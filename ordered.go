@@ -0,0 +1,178 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"slices"
+)
+
+// insertOrdered inserts the pattern preserving the leftmost-first priority
+// of the branches. The pattern is merged into the last branch, or an earlier
+// one when the branches in between never match where the pattern does.
+func (m *merger) insertOrdered(sub []*syntax.Regexp, k int, r2 *syntax.Regexp) ([]*syntax.Regexp, int) {
+	for i := k; i < len(sub); i++ {
+		// a|b|a => a|b
+		if sub[i].Equal(r2) {
+			m.absorb(sub[i], r2)
+			return sub, i
+		}
+	}
+	for i := len(sub) - 1; i >= k; i-- {
+		if r := m.mergeOrdered(sub[i], r2); r != nil {
+			sub[i] = r
+			return sub, i
+		}
+		if !disjoint(sub[i], r2) {
+			break
+		}
+	}
+	return append(sub, r2), len(sub)
+}
+
+// mergeOrdered merges the pattern into the preceding branch, in the way
+// the branch is tried first. The groups wrapping the patterns are kept.
+func (m *merger) mergeOrdered(r1, r2 *syntax.Regexp) *syntax.Regexp {
+	if isTag(r1) || isTag(r2) {
+		return nil
+	}
+	if rs1, rs2 := singleClass(r1), singleClass(r2); rs1 != nil && rs2 != nil {
+		// a|[bc] => [a-c]
+		return m.derive(charClass(append(rs1, rs2...)), r1, r2)
+	}
+	sub1, sub2 := tagSub(r1), tagSub(r2)
+	var i int
+	for ; i < len(sub1) && i < len(sub2); i++ {
+		if !isFixed(sub1[i]) || !sub1[i].Equal(sub2[i]) {
+			break
+		}
+		m.absorb(sub1[i], sub2[i])
+	}
+	if i == 0 {
+		return nil
+	}
+	// foo|foobar => foo(?:bar)??
+	// foobar|foo => foo(?:bar)?
+	// foobar|foobaz => fooba[rz]
+	sub := branches(concat(sub1[i:]...))
+	for _, rr := range branches(concat(sub2[i:]...)) {
+		sub, _ = m.insertOrdered(sub, 0, rr)
+	}
+	return m.derive(concat(append(sub1[:i:i], m.alternateOrdered(sub))...), r1, r2)
+}
+
+// alternateOrdered builds the alternation of the branches in the order.
+// The empty branch is at most one, since the same branches are dropped.
+func (m *merger) alternateOrdered(sub []*syntax.Regexp) *syntax.Regexp {
+	switch n := len(sub); {
+	case n == 1:
+		return sub[0]
+	case n > 1 && sub[0].Op == syntax.OpEmptyMatch:
+		// (?:)|x|y => (?:x|y)??
+		return m.derive(&syntax.Regexp{
+			Op: syntax.OpQuest, Flags: syntax.NonGreedy,
+			Sub: []*syntax.Regexp{m.alternateOrdered(sub[1:])},
+		}, sub[0])
+	case n > 1 && sub[n-1].Op == syntax.OpEmptyMatch:
+		// x|y|(?:) => (?:x|y)?
+		return m.derive(&syntax.Regexp{
+			Op: syntax.OpQuest, Sub: []*syntax.Regexp{m.alternateOrdered(sub[:n-1])},
+		}, sub[n-1])
+	default:
+		return &syntax.Regexp{Op: syntax.OpAlternate, Sub: sub}
+	}
+}
+
+// branches returns the alternatives of the pattern in the order of priority.
+//
+//	x?? => (?:)|x
+//	x? => x|(?:)
+func branches(r *syntax.Regexp) []*syntax.Regexp {
+	switch r.Op {
+	case syntax.OpAlternate:
+		return slices.Clone(r.Sub)
+	case syntax.OpQuest:
+		empty := &syntax.Regexp{Op: syntax.OpEmptyMatch}
+		if r.Flags&syntax.NonGreedy != 0 {
+			return append([]*syntax.Regexp{empty}, branches(r.Sub[0])...)
+		}
+		return append(branches(r.Sub[0]), empty)
+	default:
+		return []*syntax.Regexp{r}
+	}
+}
+
+// singleClass returns the class of the pattern matching a single character.
+func singleClass(r *syntax.Regexp) []rune {
+	switch r.Op {
+	case syntax.OpLiteral:
+		if len(r.Rune) == 1 {
+			return appendLiteral(nil, r.Rune[0], r.Flags)
+		}
+	case syntax.OpCharClass:
+		return slices.Clone(r.Rune)
+	}
+	return nil
+}
+
+// isFixed reports whether the pattern matches in only one way, so the common
+// prefix of such patterns can be factored without changing the priority.
+func isFixed(r *syntax.Regexp) bool {
+	switch r.Op {
+	case syntax.OpLiteral, syntax.OpCharClass, syntax.OpAnyCharNotNL, syntax.OpAnyChar,
+		syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	default:
+		return false
+	}
+}
+
+// disjoint reports whether the patterns never match at the same position,
+// where neither of them matches the empty string.
+func disjoint(r1, r2 *syntax.Regexp) bool {
+	rs1, rs2 := firstClass(r1), firstClass(r2)
+	if rs1 == nil || rs2 == nil {
+		return false
+	}
+	rs1, rs2 = normalizeClass(rs1), normalizeClass(rs2)
+	return slices.Equal(diffClass(rs1, rs2), rs1)
+}
+
+// firstClass returns the class of the first characters the pattern matches,
+// or nil if the pattern can match the empty string. The dots are expanded
+// into the classes before merging.
+func firstClass(r *syntax.Regexp) []rune {
+	switch r.Op {
+	case syntax.OpLiteral:
+		if len(r.Rune) > 0 {
+			return appendLiteral(nil, r.Rune[0], r.Flags)
+		}
+	case syntax.OpCharClass:
+		if len(r.Rune) > 0 {
+			return r.Rune
+		}
+	case syntax.OpCapture, syntax.OpPlus:
+		return firstClass(r.Sub[0])
+	case syntax.OpRepeat:
+		if r.Min > 0 {
+			return firstClass(r.Sub[0])
+		}
+	case syntax.OpConcat:
+		// \bfoo => f
+		for _, rr := range r.Sub {
+			if rr.Op < syntax.OpBeginLine || rr.Op > syntax.OpNoWordBoundary {
+				return firstClass(rr)
+			}
+		}
+	case syntax.OpAlternate:
+		var rs []rune
+		for _, rr := range r.Sub {
+			xs := firstClass(rr)
+			if xs == nil {
+				return nil
+			}
+			rs = append(rs, xs...)
+		}
+		return rs
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package rassemble
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// RangeOptions is the options for Range.
+type RangeOptions struct {
+	// ZeroPad pads the numbers with zeros to the width of the widest one.
+	ZeroPad bool
+	// LeadingZeros allows any number of leading zeros.
+	LeadingZeros bool
+}
+
+// Range builds a regular expression matching the decimal integers
+// from lo to hi inclusive. The result can be added to Assembler by
+// AddRegexp, or joined with other patterns by its String method.
+func Range(lo, hi int64, opts RangeOptions) (*syntax.Regexp, error) {
+	if lo > hi {
+		return nil, fmt.Errorf("invalid range: %d > %d", lo, hi)
+	}
+	var width int
+	if opts.ZeroPad {
+		width = max(len(magnitude(lo)), len(magnitude(hi)))
+	}
+	var m merger
+	var sub []*syntax.Regexp
+	add := func(lo, hi string, prefix ...*syntax.Regexp) {
+		if opts.LeadingZeros {
+			prefix = append(prefix, &syntax.Regexp{
+				Op: syntax.OpStar, Sub: []*syntax.Regexp{digitClass('0', '0')},
+			})
+		}
+		for _, rs := range digitRanges(lo, hi, width) {
+			sub = m.add(sub, concat(append(prefix[:len(prefix):len(prefix)], rs...)...))
+		}
+	}
+	if lo < 0 {
+		// -10..-1 => -(?:[1-9]|10)
+		add(magnitude(min(hi, -1)), magnitude(lo),
+			&syntax.Regexp{Op: syntax.OpLiteral, Rune: []rune{'-'}})
+	}
+	if hi >= 0 {
+		add(magnitude(max(lo, 0)), magnitude(hi))
+	}
+	return m.mergeSuffix(m.alternate(sub...)), nil
+}
+
+func magnitude(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	return strings.TrimPrefix(s, "-")
+}
+
+// digitRanges splits the range of the non-negative numbers into the ranges
+// of the same number of digits, padding them with zeros to the width.
+func digitRanges(lo, hi string, width int) [][]*syntax.Regexp {
+	var rss [][]*syntax.Regexp
+	for n := len(lo); n <= len(hi); n++ {
+		l, h := "1"+strings.Repeat("0", n-1), strings.Repeat("9", n)
+		if n == len(lo) {
+			l = lo
+		}
+		if n == len(hi) {
+			h = hi
+		}
+		pad := strings.Repeat("0", max(width-n, 0))
+		rss = append(rss, digitRange(pad+l, pad+h)...)
+	}
+	return rss
+}
+
+// digitRange splits the range of the numbers of the same number of digits.
+//
+//	123..456 => 12[3-9], 1[3-9][0-9], [23][0-9][0-9], 4[0-4][0-9], 45[0-6]
+func digitRange(lo, hi string) [][]*syntax.Regexp {
+	if lo == "" {
+		return [][]*syntax.Regexp{nil}
+	}
+	l, h := rune(lo[0]), rune(hi[0])
+	prepend := func(r *syntax.Regexp, rss [][]*syntax.Regexp) [][]*syntax.Regexp {
+		for i, rs := range rss {
+			rss[i] = append([]*syntax.Regexp{r}, rs...)
+		}
+		return rss
+	}
+	if l == h {
+		return prepend(digitClass(l, l), digitRange(lo[1:], hi[1:]))
+	}
+	var rss [][]*syntax.Regexp
+	if strings.Trim(lo[1:], "0") != "" {
+		// 123..199 => 12[3-9], 1[3-9][0-9]
+		rss = prepend(digitClass(l, l), digitRange(lo[1:], strings.Repeat("9", len(lo)-1)))
+		l++
+	}
+	last := strings.Trim(hi[1:], "9") != ""
+	if last {
+		h--
+	}
+	if l <= h {
+		// 200..399 => [23][0-9][0-9]
+		rs := []*syntax.Regexp{digitClass(l, h)}
+		for range len(lo) - 1 {
+			rs = append(rs, digitClass('0', '9'))
+		}
+		rss = append(rss, rs)
+	}
+	if last {
+		// 400..456 => 4[0-4][0-9], 45[0-6]
+		rss = append(rss, prepend(digitClass(h+1, h+1),
+			digitRange(strings.Repeat("0", len(hi)-1), hi[1:]))...)
+	}
+	return rss
+}
+
+func digitClass(lo, hi rune) *syntax.Regexp {
+	if lo == hi {
+		return &syntax.Regexp{Op: syntax.OpLiteral, Rune: []rune{lo}}
+	}
+	return &syntax.Regexp{Op: syntax.OpCharClass, Rune: []rune{lo, hi}}
+}
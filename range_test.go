@@ -0,0 +1,163 @@
+package rassemble
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lo, hi   int64
+		opts     RangeOptions
+		expected string
+	}{
+		{
+			name:     "single number",
+			lo:       7,
+			hi:       7,
+			expected: "7",
+		},
+		{
+			name:     "digits",
+			lo:       0,
+			hi:       9,
+			expected: "[0-9]",
+		},
+		{
+			name:     "0 to 10",
+			lo:       0,
+			hi:       10,
+			expected: "[0-9]|10",
+		},
+		{
+			name:     "days",
+			lo:       1,
+			hi:       31,
+			expected: "[1-9]|[12][0-9]|3[01]",
+		},
+		{
+			name:     "octet",
+			lo:       0,
+			hi:       255,
+			expected: "(?:[1-9]|1[0-9])?[0-9]|2(?:[0-4][0-9]|5[0-5])",
+		},
+		{
+			name:     "port numbers",
+			lo:       1000,
+			hi:       65535,
+			expected: "(?:[1-9]|[1-5][0-9])[0-9][0-9][0-9]|6(?:[0-4][0-9][0-9][0-9]|5(?:[0-4][0-9][0-9]|5(?:[0-2][0-9]|3[0-5])))",
+		},
+		{
+			name:     "same number of digits",
+			lo:       123,
+			hi:       456,
+			expected: "1(?:2[3-9]|[3-9][0-9])|[23][0-9][0-9]|4(?:[0-4][0-9]|5[0-6])",
+		},
+		{
+			name:     "zero padding",
+			lo:       0,
+			hi:       255,
+			opts:     RangeOptions{ZeroPad: true},
+			expected: "[01][0-9][0-9]|2(?:[0-4][0-9]|5[0-5])",
+		},
+		{
+			name:     "zero padding days",
+			lo:       1,
+			hi:       31,
+			opts:     RangeOptions{ZeroPad: true},
+			expected: "0[1-9]|[12][0-9]|3[01]",
+		},
+		{
+			name:     "leading zeros",
+			lo:       1,
+			hi:       31,
+			opts:     RangeOptions{LeadingZeros: true},
+			expected: "0*(?:[1-9]|[12][0-9]|3[01])",
+		},
+		{
+			name:     "negative numbers",
+			lo:       -10,
+			hi:       -1,
+			expected: "-(?:[1-9]|10)",
+		},
+		{
+			name:     "negative and positive numbers",
+			lo:       -123,
+			hi:       45,
+			expected: "-(?:[1-9][0-9]?|1(?:[01][0-9]|2[0-3]))|[1-3]?[0-9]|4[0-5]",
+		},
+		{
+			name:     "negative numbers with zero padding",
+			lo:       -123,
+			hi:       45,
+			opts:     RangeOptions{ZeroPad: true},
+			expected: "-(?:0(?:0[1-9]|[1-9][0-9])|1(?:[01][0-9]|2[0-3]))|0(?:[0-3][0-9]|4[0-5])",
+		},
+		{
+			name:     "minimum numbers",
+			lo:       math.MinInt64,
+			hi:       math.MinInt64 + 8,
+			expected: "-922337203685477580[0-8]",
+		},
+		{
+			name:     "maximum numbers",
+			lo:       math.MaxInt64 - 7,
+			hi:       math.MaxInt64,
+			expected: "922337203685477580[0-7]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Range(tc.lo, tc.hi, tc.opts)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			if got := r.String(); got != tc.expected {
+				t.Errorf("expected: %s, got: %s", tc.expected, got)
+			}
+			re := regexp.MustCompile(`\A(?:` + r.String() + `)\z`)
+			width := max(len(magnitude(tc.lo)), len(magnitude(tc.hi)))
+			for n := tc.lo - 10; n <= tc.hi+10 && n-tc.lo < 10000; n++ {
+				if n < tc.lo-10 { // overflow
+					break
+				}
+				s := magnitude(n)
+				if tc.opts.ZeroPad {
+					s = strings.Repeat("0", max(width-len(s), 0)) + s
+				}
+				if tc.opts.LeadingZeros {
+					s = "00" + s
+				}
+				if n < 0 {
+					s = "-" + s
+				}
+				if got, expected := re.MatchString(s), tc.lo <= n && n <= tc.hi; got != expected {
+					t.Errorf("%s: expected: %t, got: %t", strconv.Quote(s), expected, got)
+				}
+			}
+		})
+	}
+	if _, err := Range(2, 1, RangeOptions{}); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestRangeAssembler(t *testing.T) {
+	r, err := Range(0, 255, RangeOptions{})
+	if err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	var a Assembler
+	a.AddRegexp(r)
+	if err := a.Add("a"); err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	expected := "(?:[1-9]|1[0-9])?[0-9]|2(?:[0-4][0-9]|5[0-5])|a"
+	if got := a.String(); got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
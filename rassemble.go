@@ -2,22 +2,86 @@
 package rassemble
 
 import (
+	"errors"
+	"regexp"
 	"regexp/syntax"
 	"sort"
 	"unicode"
 )
 
-// Join patterns to build a regexp pattern.
+// Join patterns to build a regexp pattern. The patterns of only literals
+// are assembled through a trie like JoinLiterals.
 func Join(patterns []string) (string, error) {
-	var sub []*syntax.Regexp
+	return JoinWithOptions(patterns, defaultOptions)
+}
+
+// JoinSyntax joins patterns to build a parsed regular expression.
+func JoinSyntax(patterns []string) (*syntax.Regexp, error) {
+	var a Assembler
+	for _, pattern := range patterns {
+		if err := a.Add(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return a.Regexp(), nil
+}
+
+// Compile joins patterns and compiles the assembled regular expression.
+// The regular expression matches nothing if no pattern is given.
+func Compile(patterns []string) (*regexp.Regexp, error) {
+	r, err := JoinSyntax(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return compile(r)
+}
+
+func compile(r *syntax.Regexp) (*regexp.Regexp, error) {
+	if r.Op == syntax.OpAlternate && len(r.Sub) == 0 {
+		r = &syntax.Regexp{Op: syntax.OpNoMatch}
+	}
+	return regexp.Compile(r.String())
+}
+
+// MustCompile is like Compile but panics if the patterns cannot be parsed.
+func MustCompile(patterns []string) *regexp.Regexp {
+	re, err := Compile(patterns)
+	if err != nil {
+		panic("rassemble: Compile: " + err.Error())
+	}
+	return re
+}
+
+// JoinLiterals joins literal strings to build a regexp pattern.
+// Invalid UTF-8 sequences are treated as utf8.RuneError. The literals are
+// assembled through a trie, so a large number of literals can be joined.
+func JoinLiterals(literals []string) string {
+	s, _ := JoinWithOptions(literals, Options{Literal: true})
+	return s // never fails
+}
+
+// JoinWithOptions joins patterns with the options to build a regexp pattern.
+// The literals, or the patterns parsed into literals, are assembled through
+// a trie unless the options need the Assembler, so the result may differ
+// from the Assembler but is equivalent.
+func JoinWithOptions(patterns []string, opts Options) (string, error) {
+	if r, ok := joinLiterals(patterns, &opts); ok {
+		return render(r, opts.Dialect, opts.Shorthand)
+	}
+	a := NewAssembler(opts)
+	var errs []error
 	for _, pattern := range patterns {
-		r, err := syntax.Parse(pattern, syntax.PerlX|syntax.ClassNL)
-		if err != nil {
-			return "", err
+		if err := a.Add(pattern); err != nil {
+			if !opts.AllErrors {
+				return "", err
+			}
+			errs = append(errs, err)
 		}
-		sub = add(sub, breakLiterals(r))
 	}
-	return mergeSuffix(alternate(sub...)).String(), nil
+	if errs != nil {
+		return "", errors.Join(errs...)
+	}
+	return render(a.Regexp(), opts.Dialect, opts.Shorthand)
 }
 
 func breakLiterals(r *syntax.Regexp) *syntax.Regexp {
@@ -42,26 +106,41 @@ func breakLiterals(r *syntax.Regexp) *syntax.Regexp {
 	return r
 }
 
-func add(sub []*syntax.Regexp, r2 *syntax.Regexp) []*syntax.Regexp {
-	if r2.Op == syntax.OpAlternate {
-		for _, r2 := range r2.Sub {
-			sub = add(sub, r2)
-		}
-		return sub
+// merger merges the regular expressions. When src is not nil, it records
+// the indices of the patterns each node derives from (see SourceMap).
+// With ordered, the branches are merged preserving the leftmost-first priority.
+type merger struct {
+	src     map[*syntax.Regexp][]int
+	ordered bool
+}
+
+func (m *merger) add(sub []*syntax.Regexp, r2 *syntax.Regexp) []*syntax.Regexp {
+	sub, _ = m.insert(sub, 0, r2)
+	return sub
+}
+
+func (m *merger) insert(sub []*syntax.Regexp, k int, r2 *syntax.Regexp) ([]*syntax.Regexp, int) {
+	if m.ordered {
+		return m.insertOrdered(sub, k, r2)
 	}
-	for i, r1 := range sub {
+	for i := k; i < len(sub); i++ {
+		r1 := sub[i]
 		if r1.Equal(r2) {
-			return sub
+			m.absorb(r1, r2)
+			return sub, i
 		}
-		if r := mergePrefix(r1, r2); r != nil {
+		if r := m.mergePrefix(r1, r2); r != nil {
 			sub[i] = r
-			return sub
+			return sub, i
 		}
 	}
-	return append(sub, r2)
+	return append(sub, r2), len(sub)
 }
 
-func mergePrefix(r1, r2 *syntax.Regexp) *syntax.Regexp {
+func (m *merger) mergePrefix(r1, r2 *syntax.Regexp) *syntax.Regexp {
+	if isTag(r1) || isTag(r2) {
+		return m.mergeTag(r1, r2)
+	}
 	if r1.Op > r2.Op {
 		r1, r2 = r2, r1
 	}
@@ -71,35 +150,42 @@ func mergePrefix(r1, r2 *syntax.Regexp) *syntax.Regexp {
 		case syntax.OpLiteral, syntax.OpCharClass,
 			syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
 			// (?:)|x+ => x*, etc.
-			return quest(r2)
+			return m.derive(quest(r2), r1, r2)
+		case syntax.OpRepeat:
+			// (?:)|x{1,3} => x{0,3}
+			if r := m.mergeRepeat(r1, r2); r != nil {
+				return r
+			}
+			// (?:)|x{2,3} => (?:x{2,3})?
+			return m.derive(quest(r2), r1, r2)
 		}
 	case syntax.OpLiteral:
 		switch r2.Op {
 		case syntax.OpCharClass:
 			// a|[bc] => [a-c]
 			// (?i:a)|[bc] => [Aa-c]
-			return charClass(appendLiteral(r2.Rune, r1.Rune[0], r1.Flags))
+			return m.derive(charClass(appendLiteral(r2.Rune, r1.Rune[0], r1.Flags)), r1, r2)
 		case syntax.OpQuest:
-			if r2 := r2.Sub[0]; r2.Op == syntax.OpCharClass {
+			if rr := r2.Sub[0]; rr.Op == syntax.OpCharClass {
 				// a|[bc]? => [a-c]?
 				// (?i:a)|[bc]? => [Aa-c]?
-				return quest(charClass(appendLiteral(r2.Rune, r1.Rune[0], r1.Flags)))
+				return quest(m.derive(charClass(appendLiteral(rr.Rune, r1.Rune[0], r1.Flags)), r1, r2))
 			}
 		}
 	case syntax.OpCharClass:
 		switch r2.Op {
 		case syntax.OpCharClass:
 			// [a-c]|[d-f] => [a-f]
-			return charClass(append(r1.Rune, r2.Rune...))
+			return m.derive(charClass(append(r1.Rune, r2.Rune...)), r1, r2)
 		case syntax.OpQuest:
-			switch r2 := r2.Sub[0]; r2.Op {
+			switch rr := r2.Sub[0]; rr.Op {
 			case syntax.OpLiteral:
 				// [ab]|c? => [a-c]?
 				// [ab]|(?i:c)? => [Ca-c]?
-				return quest(charClass(appendLiteral(r1.Rune, r2.Rune[0], r2.Flags)))
+				return quest(m.derive(charClass(appendLiteral(r1.Rune, rr.Rune[0], rr.Flags)), r1, r2))
 			case syntax.OpCharClass:
 				// [ab]|[cd]? => [a-d]?
-				return quest(charClass(append(r1.Rune, r2.Rune...)))
+				return quest(m.derive(charClass(append(r1.Rune, rr.Rune...)), r1, r2))
 			}
 		}
 	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
@@ -107,32 +193,105 @@ func mergePrefix(r1, r2 *syntax.Regexp) *syntax.Regexp {
 			// x*|x => x*
 			// x+|x => x+
 			// x?|x => x?
+			m.absorb(r1.Sub[0], r2)
 			return r1
 		}
 		if r1.Op < r2.Op && r2.Op <= syntax.OpQuest && r1.Sub[0].Equal(r2.Sub[0]) {
 			// x*|x+ => x*
 			// x*|x? => x*
 			// x+|x? => x*
-			return &syntax.Regexp{Op: syntax.OpStar, Sub: r1.Sub}
+			m.absorb(r1.Sub[0], r2.Sub[0])
+			return m.derive(&syntax.Regexp{Op: syntax.OpStar, Sub: r1.Sub}, r1, r2)
 		}
 	case syntax.OpConcat:
-		return mergePrefixConcat(r1, r2)
+		return m.mergePrefixConcat(r1, r2)
 	}
 	switch r2.Op {
 	case syntax.OpConcat:
-		return mergePrefixConcat(r2, r1)
+		return m.mergePrefixConcat(r2, r1)
 	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
 		if r1.Equal(r2.Sub[0]) {
 			// x|x* => x*
 			// x|x? => x?
 			// x|x+ => x+
+			m.absorb(r2.Sub[0], r1)
 			return r2
 		}
+	case syntax.OpRepeat:
+		return m.mergeRepeat(r1, r2)
 	}
 	return nil
 }
 
-func mergePrefixConcat(r1, r2 *syntax.Regexp) *syntax.Regexp {
+func (m *merger) mergeRepeat(r1, r2 *syntax.Regexp) *syntax.Regexp {
+	sub, min1, max1 := repeatRange(r1)
+	if sub != nil && !sub.Equal(r2.Sub[0]) ||
+		r1.Op >= syntax.OpStar && r1.Op <= syntax.OpRepeat &&
+			(r1.Flags^r2.Flags)&syntax.NonGreedy != 0 {
+		return nil
+	}
+	min2, max2 := r2.Min, r2.Max
+	if min1 > min2 {
+		min1, max1, min2, max2 = min2, max2, min1, max1
+	}
+	if max1 >= 0 && max1+1 < min2 {
+		// x{2}|x{4} =/> x{2,4}
+		return nil
+	}
+	if max1 < 0 || max2 < 0 {
+		max1 = -1
+	} else {
+		max1 = max(max1, max2)
+	}
+	if sub != nil {
+		m.absorb(r2.Sub[0], sub)
+	}
+	// x{2}|x{3} => x{2,3}
+	// x{2,}|x => x+
+	// x?|x{2} => x{0,2}
+	return m.derive(repeat(r2.Sub[0], min1, max1, r2.Flags), r1, r2)
+}
+
+// repeatRange returns the sub-expression and the range of the repetition,
+// where the maximum is -1 for no limit, and the sub-expression is nil for
+// the empty match.
+func repeatRange(r *syntax.Regexp) (*syntax.Regexp, int, int) {
+	switch r.Op {
+	case syntax.OpEmptyMatch:
+		return nil, 0, 0
+	case syntax.OpStar:
+		return r.Sub[0], 0, -1
+	case syntax.OpPlus:
+		return r.Sub[0], 1, -1
+	case syntax.OpQuest:
+		return r.Sub[0], 0, 1
+	case syntax.OpRepeat:
+		return r.Sub[0], r.Min, r.Max
+	default:
+		return r, 1, 1
+	}
+}
+
+func repeat(r *syntax.Regexp, min, max int, flags syntax.Flags) *syntax.Regexp {
+	var op syntax.Op
+	switch {
+	case min == 1 && max == 1:
+		return r
+	case min == 0 && max == 1:
+		op = syntax.OpQuest
+	case min == 0 && max < 0:
+		op = syntax.OpStar
+	case min == 1 && max < 0:
+		op = syntax.OpPlus
+	default:
+		op = syntax.OpRepeat
+	}
+	return &syntax.Regexp{
+		Op: op, Flags: flags, Sub: []*syntax.Regexp{r}, Min: min, Max: max,
+	}
+}
+
+func (m *merger) mergePrefixConcat(r1, r2 *syntax.Regexp) *syntax.Regexp {
 	if r2.Op == syntax.OpConcat {
 		var i int
 		for ; i < len(r1.Sub) && i < len(r2.Sub); i++ {
@@ -142,37 +301,43 @@ func mergePrefixConcat(r1, r2 *syntax.Regexp) *syntax.Regexp {
 		}
 		if i > 0 {
 			// x*y*z*w*|x*y*u*v* => x*y*(?:z*w*|u*v*)
-			return concat(
+			rest1 := m.derive(concat(r1.Sub[i:]...), r1)
+			rest2 := m.derive(concat(r2.Sub[i:]...), r2)
+			for j := range i {
+				m.absorb(r1.Sub[j], r2.Sub[j])
+			}
+			return m.derive(concat(
 				append(
 					append(make([]*syntax.Regexp, 0, i+1), r1.Sub[:i]...),
-					alternate(concat(r1.Sub[i:]...), concat(r2.Sub[i:]...)),
+					m.alternate(rest1, rest2),
 				)...,
-			)
+			), r1, r2)
 		}
 	} else if r1.Sub[0].Equal(r2) {
 		// x*y*z*|x* => x*(?:y*z*)?
-		return concat(r2, quest(concat(r1.Sub[1:]...)))
+		m.absorb(r2, r1.Sub[0])
+		return m.derive(concat(r2, m.derive(quest(concat(r1.Sub[1:]...)), r1, r2)), r1)
 	}
 	return nil
 }
 
-func mergeSuffix(r *syntax.Regexp) *syntax.Regexp {
+func (m *merger) mergeSuffix(r *syntax.Regexp) *syntax.Regexp {
 	for i, rr := range r.Sub {
-		r.Sub[i] = mergeSuffix(rr)
+		r.Sub[i] = m.mergeSuffix(rr)
 	}
 	switch r.Op {
 	case syntax.OpAlternate:
-		sub, k, rs, merge := r.Sub, -1, r.Rune0[:0], false
+		sub, k, rs, cs := r.Sub, -1, r.Rune0[:0], []*syntax.Regexp(nil)
 		for i := 0; i < len(sub); i++ {
 			r1 := sub[i]
 			for j := i + 1; j < len(sub); j++ {
 				r2 := sub[j]
-				if r := mergeSuffixConcat(r1, r2); r != nil {
+				if r := m.mergeSuffixConcat(r1, r2); r != nil {
 					r1, j, sub = r, j-1, append(sub[:j], sub[j+1:]...)
 				}
 			}
 			if r1 != sub[i] {
-				sub[i] = mergeSuffix(r1)
+				sub[i] = m.mergeSuffix(r1)
 				continue
 			}
 			// merge literals and character classes here
@@ -185,28 +350,28 @@ func mergeSuffix(r *syntax.Regexp) *syntax.Regexp {
 			default:
 				continue
 			}
-			if k < 0 {
+			if cs = append(cs, r1); k < 0 {
 				k = i
 			} else {
-				i, sub, merge = i-1, append(sub[:i], sub[i+1:]...), true
+				i, sub = i-1, append(sub[:i], sub[i+1:]...)
 			}
 		}
-		if merge {
+		if len(cs) > 1 {
 			// (?:a|b|[c-e]) => [a-e]
-			sub[k] = charClass(rs)
+			sub[k] = m.derive(charClass(rs), cs...)
 		}
-		return alternate(sub...)
+		return m.derive(m.alternate(sub...), r)
 	case syntax.OpQuest:
-		if r := r.Sub[0]; r.Op == syntax.OpAlternate {
-			for i, rr := range r.Sub {
-				if rr.Op == syntax.OpLiteral {
-					for _, rs := range r.Sub {
+		if rr := r.Sub[0]; rr.Op == syntax.OpAlternate {
+			for i, rl := range rr.Sub {
+				if rl.Op == syntax.OpLiteral {
+					for _, rs := range rr.Sub {
 						if rs.Op == syntax.OpConcat &&
 							rs.Sub[len(rs.Sub)-1].Op == syntax.OpQuest &&
-							rr.Equal(rs.Sub[len(rs.Sub)-1].Sub[0]) {
+							rl.Equal(rs.Sub[len(rs.Sub)-1].Sub[0]) {
 							// (?:ab?|b)? => (?:ab?|b?) => a?b?
-							r.Sub[i] = quest(rr)
-							return mergeSuffix(r)
+							rr.Sub[i] = quest(rl)
+							return m.derive(m.mergeSuffix(rr), r)
 						}
 					}
 				}
@@ -214,13 +379,13 @@ func mergeSuffix(r *syntax.Regexp) *syntax.Regexp {
 		}
 		return r
 	case syntax.OpConcat:
-		return flattenConcat(r)
+		return m.derive(flattenConcat(r), r)
 	default:
 		return r
 	}
 }
 
-func mergeSuffixConcat(r1, r2 *syntax.Regexp) *syntax.Regexp {
+func (m *merger) mergeSuffixConcat(r1, r2 *syntax.Regexp) *syntax.Regexp {
 	if r1.Op != syntax.OpConcat {
 		if r2.Op != syntax.OpConcat {
 			return nil
@@ -236,25 +401,100 @@ func mergeSuffixConcat(r1, r2 *syntax.Regexp) *syntax.Regexp {
 		}
 		if i > 0 {
 			// x*y*z*w*|u*v*z*w* => (?:x*y*|u*v*)z*w*
-			return concat(
+			rest1 := m.derive(concat(r1.Sub[:len(r1.Sub)-i]...), r1)
+			rest2 := m.derive(concat(r2.Sub[:len(r2.Sub)-i]...), r2)
+			for j := range i {
+				m.absorb(r1.Sub[len(r1.Sub)-1-j], r2.Sub[len(r2.Sub)-1-j])
+			}
+			return m.derive(concat(
 				append(
-					[]*syntax.Regexp{
-						alternate(
-							concat(r1.Sub[:len(r1.Sub)-i]...),
-							concat(r2.Sub[:len(r2.Sub)-i]...),
-						),
-					},
+					[]*syntax.Regexp{m.alternate(rest1, rest2)},
 					r1.Sub[len(r1.Sub)-i:]...,
 				)...,
-			)
+			), r1, r2)
 		}
 	} else if r1.Sub[len(r1.Sub)-1].Equal(r2) {
 		// x*y*z*|z* => (?:x*y*)?z*
-		return concat(quest(concat(r1.Sub[:len(r1.Sub)-1]...)), r2)
+		m.absorb(r2, r1.Sub[len(r1.Sub)-1])
+		return m.derive(concat(m.derive(quest(concat(r1.Sub[:len(r1.Sub)-1]...)), r1, r2), r2), r1)
 	}
 	return nil
 }
 
+// foldRepeats folds the successive repetitions of the same expression
+// into a counted repetition, when the pattern gets shorter.
+func (m *merger) foldRepeats(r *syntax.Regexp) *syntax.Regexp {
+	for i, rr := range r.Sub {
+		r.Sub[i] = m.foldRepeats(rr)
+	}
+	sub := r.Sub
+	if r.Op != syntax.OpConcat {
+		sub = []*syntax.Regexp{r}
+	}
+	rs := make([]*syntax.Regexp, 0, len(sub))
+	for i := 0; i < len(sub); {
+		x, lo, hi := repeatRun(sub[i])
+		j := i + 1
+		for ; j < len(sub); j++ {
+			y, l, h := repeatRun(sub[j])
+			if !x.Equal(y) {
+				break
+			}
+			lo, hi = sumRange(lo, hi, l, h)
+		}
+		// 00000000 => 0{8}
+		// 0000000?0? => 0{6,8}
+		if lo <= 1000 && hi <= 1000 {
+			if rr := repeat(x, lo, hi, 0); len(rr.String()) < len(concat(sub[i:j]...).String()) {
+				rs, i = append(rs, m.derive(rr, sub[i:j]...)), j
+				continue
+			}
+		}
+		rs, i = append(rs, sub[i:j]...), j
+	}
+	if r.Op != syntax.OpConcat {
+		return rs[0]
+	}
+	return m.derive(concat(rs...), r)
+}
+
+// repeatRun returns the expression and the range of the greedy repetition,
+// looking into the optional run of the same expression.
+func repeatRun(r *syntax.Regexp) (*syntax.Regexp, int, int) {
+	if r.Flags&syntax.NonGreedy != 0 {
+		return r, 1, 1
+	}
+	switch r.Op {
+	case syntax.OpQuest:
+		// (?:00?)? => 0{0,2}
+		if x, lo, hi := repeatRun(r.Sub[0]); lo <= 1 {
+			return x, 0, hi
+		}
+		return repeatRange(r)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpRepeat:
+		return repeatRange(r)
+	case syntax.OpConcat:
+		x, lo, hi := repeatRun(r.Sub[0])
+		for _, rr := range r.Sub[1:] {
+			y, l, h := repeatRun(rr)
+			if !x.Equal(y) {
+				return r, 1, 1
+			}
+			lo, hi = sumRange(lo, hi, l, h)
+		}
+		return x, lo, hi
+	default:
+		return r, 1, 1
+	}
+}
+
+func sumRange(lo1, hi1, lo2, hi2 int) (int, int) {
+	if hi1 < 0 || hi2 < 0 {
+		return lo1 + lo2, -1
+	}
+	return lo1 + lo2, hi1 + hi2
+}
+
 func flattenConcat(r *syntax.Regexp) *syntax.Regexp {
 	n := len(r.Sub)
 	for _, rr := range r.Sub {
@@ -284,29 +524,29 @@ func concat(sub ...*syntax.Regexp) *syntax.Regexp {
 	}
 }
 
-func alternate(sub ...*syntax.Regexp) *syntax.Regexp {
+func (m *merger) alternate(sub ...*syntax.Regexp) *syntax.Regexp {
 	switch len(sub) {
 	case 1:
 		return sub[0]
 	case 2:
 		r1, r2 := sub[0], sub[1]
-		if r := mergePrefix(r1, r2); r != nil {
+		if r := m.mergePrefix(r1, r2); r != nil {
 			return r
 		}
 		if r2.Op == syntax.OpEmptyMatch {
 			// x*y*|(?:) => (?:x*y*)?
-			return quest(r1)
+			return m.derive(quest(r1), r1, r2)
 		}
 		switch r1.Op {
 		case syntax.OpEmptyMatch:
 			// (?:)|x*y* => (?:x*y*)?
-			return quest(r2)
+			return m.derive(quest(r2), r1, r2)
 		case syntax.OpAlternate:
 			// (?:x*|y*)|z* => x*|y*|z*
-			return alternate(add(r1.Sub, r2)...)
+			return m.derive(m.alternate(m.add(r1.Sub, r2)...), r1)
 		case syntax.OpQuest:
 			// x?|y* => (?:x|y*)?
-			return quest(alternate(r1.Sub[0], r2))
+			return m.derive(quest(m.alternate(r1.Sub[0], r2)), r1)
 		}
 		fallthrough
 	default:
@@ -384,12 +624,7 @@ func charClass(rs []rune) *syntax.Regexp {
 			}
 		}
 	}
-	rs = rs[:i+2]
-	if len(rs) == 2 && rs[0] == 0 && rs[1] == unicode.MaxRune {
-		// [^a]|a => (?s:.)
-		return &syntax.Regexp{Op: syntax.OpAnyChar}
-	}
-	return &syntax.Regexp{Op: syntax.OpCharClass, Rune: rs}
+	return &syntax.Regexp{Op: syntax.OpCharClass, Rune: rs[:i+2]}
 }
 
 func appendLiteral(rs []rune, r rune, flags syntax.Flags) []rune {
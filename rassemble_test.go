@@ -1,437 +1,938 @@
 package rassemble
 
-import "testing"
+import (
+	"math/rand/v2"
+	"regexp/syntax"
+	"slices"
+	"testing"
+)
+
+var joinTestCases = []struct {
+	name     string
+	patterns []string
+	expected string
+	trie     string // the result of Join through the trie if it differs
+}{
+	{
+		name:     "empty",
+		patterns: []string{},
+		expected: "",
+	},
+	{
+		name:     "empty literal",
+		patterns: []string{""},
+		expected: "(?:)",
+	},
+	{
+		name:     "empty literals",
+		patterns: []string{"", ""},
+		expected: "(?:)",
+	},
+	{
+		name:     "single literal",
+		patterns: []string{"abc"},
+		expected: "abc",
+	},
+	{
+		name:     "single literal with flag",
+		patterns: []string{"(?i:abc)"},
+		expected: "(?i:ABC)",
+	},
+	{
+		name:     "single literal with multiple flags",
+		patterns: []string{"(?ims:^a.b.c$)"},
+		expected: "(?ims:^A.B.C$)",
+	},
+	{
+		name:     "multiple literals",
+		patterns: []string{"abc", "def", "ghi"},
+		expected: "abc|def|ghi",
+	},
+	{
+		name:     "multiple literals with same flag",
+		patterns: []string{"(?i:abc)", "(?i:def)", "(?i:ghi)"},
+		expected: "(?i:ABC|DEF|GHI)",
+	},
+	{
+		name:     "multiple literals with different flags",
+		patterns: []string{"(?i:abc)", "(?m:d.$)", "(?s:^g.i)"},
+		expected: "(?-s:(?i:ABC)|(?m:d.$)|(?ms:^g.i))",
+	},
+	{
+		name:     "multiple characters with different flags",
+		patterns: []string{"a", "b", "(?i:c)?", "d"},
+		expected: "[Ca-d]?",
+	},
+	{
+		name:     "same literals",
+		patterns: []string{"abc", "def", "abc", "def"},
+		expected: "abc|def",
+	},
+	{
+		name:     "same prefixes with different length",
+		patterns: []string{"abc", "ab", "ad", "a"},
+		expected: "a(?:bc?|d)?",
+	},
+	{
+		name:     "same prefixes with different length",
+		patterns: []string{"abcd", "abcf", "abc", "abce", "abcgh", "abdc"},
+		expected: "ab(?:c(?:[d-f]|gh)?|dc)",
+	},
+	{
+		name:     "same prefixes with same length",
+		patterns: []string{"abcde", "abcfg", "abcgh"},
+		expected: "abc(?:de|fg|gh)",
+	},
+	{
+		name:     "same prefixes in increasing length order",
+		patterns: []string{"a", "ab", "abc", "abcd"},
+		expected: "a(?:b(?:cd?)?)?",
+	},
+	{
+		name:     "same prefixes in decreasing length order",
+		patterns: []string{"abcd", "abc", "ab", "a"},
+		expected: "a(?:b(?:cd?)?)?",
+	},
+	{
+		name:     "same prefixes with same flag",
+		patterns: []string{"(?i:abc)", "(?i:ab)", "(?i:ad)", "(?i:a)"},
+		expected: "(?i:A(?:BC?|D)?)",
+	},
+	{
+		name:     "same prefixes with various flags",
+		patterns: []string{"(?i:abc)", "(?:a.*b$)", "(?im:ad$|ae)", "(?sm:a.$)", "(?U:a.*c$)"},
+		expected: "(?-s:(?im:A(?:BC|D$|E))|(?m:a(?:.*b|(?s:.)|.*?c)$))",
+	},
+	{
+		name:     "same prefix and suffix",
+		patterns: []string{"abcdefg", "abcfg", "abefg", "befg", "beefg"},
+		expected: "(?:ab(?:c(?:de)?|e)|bee?)fg",
+	},
+	{
+		name:     "same prefix and suffix with double quests",
+		patterns: []string{"abcd", "abd", "acd", "ad"},
+		expected: "ab?c?d",
+	},
+	{
+		name:     "same prefix and suffix with triple quests",
+		patterns: []string{"abcde", "acde", "abde", "abce", "abe", "ace", "ade", "ae"},
+		expected: "ab?c?d?e",
+	},
+	{
+		name:     "multiple prefix groups",
+		patterns: []string{"abc", "ab", "abcd", "a", "bcd", "bcdef", "cdef", "cdeh"},
+		expected: "a(?:b(?:cd?)?)?|bcd(?:ef)?|cde[fh]",
+	},
+	{
+		name:     "merge literal to quest",
+		patterns: []string{"abc(?:def)?", "abc"},
+		expected: "abc(?:def)?",
+	},
+	{
+		name:     "merge literal to star",
+		patterns: []string{"abc(?:def)*", "abcdef", "abc"},
+		expected: "abc(?:def)*",
+	},
+	{
+		name:     "merge literal to plus",
+		patterns: []string{"abc(?:def)+", "abcdef", "abc"},
+		expected: "abc(?:def)*",
+	},
+	{
+		name:     "merge literal to alternate",
+		patterns: []string{"abc(?:de|f)", "abc"},
+		expected: "abc(?:de|f)?",
+	},
+	{
+		name:     "merge literal to alternate with plus",
+		patterns: []string{"abc(?:de|[f-h]+)", "abc", "abc"},
+		expected: "abc(?:de|[f-h]*)",
+	},
+	{
+		name:     "merge literal to concat",
+		patterns: []string{"abca*b*", "abc"},
+		expected: "abc(?:a*b*)?",
+	},
+	{
+		name:     "merge literal to concat",
+		patterns: []string{"abca*b*", "abcde"},
+		expected: "abc(?:a*b*|de)",
+	},
+	{
+		name:     "merge literal to alternate in quest",
+		patterns: []string{"abc(?:de|fh)?", "abcff", "abcf", "abchh"},
+		expected: "abc(?:de|f[fh]?|hh)?",
+	},
+	{
+		name:     "merge literal to quest with suffix",
+		patterns: []string{"abc(?:def)?ghi", "abcd"},
+		expected: "abc(?:(?:def)?ghi|d)",
+	},
+	{
+		name:     "merge literal to alternate with same prefix",
+		patterns: []string{"abcfd|def", "abcdef", "abcfe"},
+		expected: "abc(?:f[de]|def)|def",
+	},
+	{
+		name:     "merge literal to alternate with different prefix",
+		patterns: []string{"abc|def", "ghi"},
+		expected: "abc|def|ghi",
+	},
+	{
+		name:     "character class",
+		patterns: []string{"a", "1", "z", "2"},
+		expected: "[12az]",
+	},
+	{
+		name:     "character class with prefix",
+		patterns: []string{"aa", "ab"},
+		expected: "a[ab]",
+	},
+	{
+		name:     "character class in prefix",
+		patterns: []string{"abcde", "abc", "bbcde", "bbc", "cbcde", "cbc"},
+		expected: "[a-c]bc(?:de)?",
+	},
+	{
+		name:     "add character class to a character",
+		patterns: []string{"d?", "[a-c]", "e"},
+		expected: "[a-e]?",
+	},
+	{
+		name:     "add character class to a character class",
+		patterns: []string{"[a-c]", "[e-f]", "d"},
+		expected: "[a-f]",
+	},
+	{
+		name:     "add complex character class to a complex character class",
+		patterns: []string{"[i-kea-c]", "[f-hd]"},
+		expected: "[a-k]",
+	},
+	{
+		name:     "add character to character class negation",
+		patterns: []string{"[^0-9]", "3", "5"},
+		expected: "[^0-246-9]",
+	},
+	{
+		name:     "add quest of character to character class negation",
+		patterns: []string{"[^0-9]", "3?", "5"},
+		expected: "[^0-246-9]?",
+	},
+	{
+		name:     "add character to character class negation to match anything",
+		patterns: []string{"[^0]", "0"},
+		expected: "(?s:.)",
+	},
+	{
+		name:     "add characters to dot",
+		patterns: []string{"a.", "ab", "b.", "b\n", "c[^\n]"},
+		expected: "(?-s:[ac].|(?s:b.))",
+	},
+	{
+		name:     "add character to negated class",
+		patterns: []string{`\W`, "a"},
+		expected: "[^0-9A-Z_b-z]",
+	},
+	{
+		name:     "merge literal prefix rather than character class",
+		patterns: []string{"a", "c", "e", "ab", "cd", "ef"},
+		expected: "ab?|cd?|ef?",
+	},
+	{
+		name:     "add character class to a character class negation",
+		patterns: []string{"[a-d]", "[^c-g]", "f"},
+		expected: "[^eg]",
+	},
+	{
+		name:     "successive character class",
+		patterns: []string{"aa", "ab", "ac"},
+		expected: "a[a-c]",
+	},
+	{
+		name:     "successive character class in random order",
+		patterns: []string{"ac", "aa", "ae", "ab", "ad"},
+		expected: "a[a-e]",
+	},
+	{
+		name:     "add case insensitive literal to a literal",
+		patterns: []string{"a", "(?i:b)", "c"},
+		expected: "[Ba-c]",
+	},
+	{
+		name:     "add case insensitive literal to a character class",
+		patterns: []string{"(?i:a)", "[b-e]", "(?i:f)"},
+		expected: "[AFa-f]",
+	},
+	{
+		name:     "add case insensitive character class to a character class",
+		patterns: []string{"[a-c]", "(?i:[d-f])", "[g-i]"},
+		expected: "[D-Fa-i]",
+	},
+	{
+		name:     "add case insensitive literal to a quest of character class",
+		patterns: []string{"(?i:a)", "[b-e]?", "(?i:f)"},
+		expected: "[AFa-f]?",
+	},
+	{
+		name:     "numbers",
+		patterns: []string{"1", "9", "2", "6", "3"},
+		expected: "[1-369]",
+	},
+	{
+		name:     "numbers 0 to 5",
+		patterns: []string{"0", "4", "3", "5", "1", "2"},
+		expected: "[0-5]",
+	},
+	{
+		name:     "numbers 0 to 10",
+		patterns: []string{"1", "9", "2", "6", "3", "7", "10", "8", "0", "5", "4"},
+		expected: "10?|[02-9]",
+	},
+	{
+		name:     "numbers with prefix",
+		patterns: []string{"a2", "a1", "a0", "a8", "a3", "a5", "a6", "a4", "a7", "a11", "a2", "a9", "a0", "a10"},
+		expected: "a(?:[02-9]|1[01]?)",
+	},
+	{
+		name:     "add empty literal to quest",
+		patterns: []string{"abc", "", ""},
+		expected: "(?:abc)?",
+	},
+	{
+		name:     "add empty literal to plus and star",
+		patterns: []string{"(?:abc)+", "", ""},
+		expected: "(?:abc)*",
+	},
+	{
+		name:     "add empty literal to character class",
+		patterns: []string{"[135]", "", "7"},
+		expected: "[1357]?",
+	},
+	{
+		name:     "add empty literal to alternate with quest",
+		patterns: []string{"abc", "b", "", ""},
+		expected: "abc|b?",
+		trie:     "(?:abc|b)?",
+	},
+	{
+		name:     "add empty literal to alternate with plus and star",
+		patterns: []string{"abc", "b+", "", ""},
+		expected: "abc|b*",
+	},
+	{
+		name:     "add literal to empty literal",
+		patterns: []string{"", "abc", ""},
+		expected: "(?:abc)?",
+	},
+	{
+		name:     "add literal with a flag to empty literal",
+		patterns: []string{"", "(?i:abc)", ""},
+		expected: "(?i:(?:ABC)?)",
+	},
+	{
+		name:     "add quest to empty literal",
+		patterns: []string{"", "(?:abc)?"},
+		expected: "(?:abc)?",
+	},
+	{
+		name:     "add quest to quest",
+		patterns: []string{"(?:abc)?", "(?:abc)?"},
+		expected: "(?:abc)?",
+	},
+	{
+		name:     "add star to empty literal",
+		patterns: []string{"", "(?:abc)*"},
+		expected: "(?:abc)*",
+	},
+	{
+		name:     "add star and plus to character class",
+		patterns: []string{"a[a-c]c", "a[a-c]*c", "a[a-c]+c", "a[a-d]+c"},
+		expected: "a(?:[a-c]*|[a-d]+)c",
+	},
+	{
+		name:     "add quest and plus to character class",
+		patterns: []string{"a[a-c]c", "aac", "a[a-c]?c", "a[a-c]+c", "a[a-d]c"},
+		expected: "a(?:[a-c]*|[a-d])c",
+	},
+	{
+		name:     "add quest of character class to literal",
+		patterns: []string{"abc", "a[a-c]?c"},
+		expected: "a[a-c]?c",
+	},
+	{
+		name:     "add quest of character class to character class",
+		patterns: []string{"abc", "adc", "a[a-c]?c"},
+		expected: "a[a-d]?c",
+	},
+	{
+		name:     "add plus to empty literal",
+		patterns: []string{"", "(?:abc)+"},
+		expected: "(?:abc)*",
+	},
+	{
+		name:     "add plus to literal",
+		patterns: []string{"abc", "(?:ab)+", "(?:abc)+"},
+		expected: "(?:abc)+|(?:ab)+",
+	},
+	{
+		name:     "add plus to quest",
+		patterns: []string{"(?:abc)?", "(?:ab)+", "(?:abc)+"},
+		expected: "(?:abc)*|(?:ab)+",
+	},
+	{
+		name:     "add character class to empty literal",
+		patterns: []string{"", "[a-c]"},
+		expected: "[a-c]?",
+	},
+	{
+		name:     "add alternate",
+		patterns: []string{"a", "[a-c]|bb", "cc|d"},
+		expected: "[a-d]|bb|cc",
+	},
+	{
+		name:     "add repeat to repeat",
+		patterns: []string{"a{2}", "a{3}", "a{4}"},
+		expected: "a{2,4}",
+	},
+	{
+		name:     "add repeat to overlapping repeat",
+		patterns: []string{"a{1,2}", "a{4,5}", "a{2,3}"},
+		expected: "a{1,5}",
+	},
+	{
+		name:     "add repeat to distant repeat",
+		patterns: []string{"a{2}", "a{4}", "b{3}"},
+		expected: "a{2}|a{4}|b{3}",
+	},
+	{
+		name:     "add repeat to non-greedy repeat",
+		patterns: []string{"a{2}?", "a{3}?", "a{4}"},
+		expected: "a{2,3}?|a{4}",
+	},
+	{
+		name:     "add literal to unbounded repeat",
+		patterns: []string{"a{2,}", "a"},
+		expected: "a+",
+	},
+	{
+		name:     "add quest to repeat",
+		patterns: []string{"(?:ab)?", "(?:ab){2}"},
+		expected: "(?:ab){0,2}",
+	},
+	{
+		name:     "add repeat to star and plus",
+		patterns: []string{"a*", "a{3}", "b+", "b{0,3}"},
+		expected: "a*|b*",
+	},
+	{
+		name:     "add repeat to repeat of one",
+		patterns: []string{"a{1}", "a", "b{0,1}", "b{1}"},
+		expected: "a|b?",
+	},
+	{
+		name:     "add empty literal to repeat",
+		patterns: []string{"a{1,3}", "", "b{2,3}", ""},
+		expected: "a{0,3}|b{2,3}",
+	},
+	{
+		name:     "add empty literal to repeat not starting from one",
+		patterns: []string{"a{2,3}", "", "b{1,3}"},
+		expected: "(?:a{2,3}|b{1,3})?",
+	},
+	{
+		name:     "repeats with same prefix",
+		patterns: []string{`\d{3}`, `\d{3}-\d{4}`, "ab{2}c", "ab{3}c", "ab"},
+		expected: "[0-9]{3}(?:-[0-9]{4})?|a(?:b{2,3}c|b)",
+	},
+	{
+		name:     "factor anchors",
+		patterns: []string{"^foo", "^bar", "baz$", "qux$"},
+		expected: "(?m:^(?:foo|bar)|(?:baz|qux)$)",
+	},
+	{
+		name:     "factor anchors in different order",
+		patterns: []string{`\b^foo`, `^\bbar`, `\bbaz\b`},
+		expected: `(?m:^\b(?:foo|bar)|\bbaz\b)`,
+	},
+	{
+		name:     "remove redundant anchors",
+		patterns: []string{`\A^foo`, `\Abar`, `\b\bbaz`, `qux$\z`},
+		expected: `\A(?:foo|bar)|\bbaz|qux\z`,
+	},
+	{
+		name:     "merge suffix",
+		patterns: []string{"abcde", "cde", "bde"},
+		expected: "(?:(?:ab)?c|b)de",
+		trie:     "(?:abc|[bc])de",
+	},
+	{
+		name:     "merge suffix in increasing length order",
+		patterns: []string{"e", "de", "cde", "bcde", "abcde"},
+		expected: "(?:(?:(?:a?b)?c)?d)?e",
+	},
+	{
+		name:     "merge suffix in decreasing length order",
+		patterns: []string{"abcde", "bcde", "cde", "de", "e"},
+		expected: "(?:(?:(?:a?b)?c)?d)?e",
+	},
+	{
+		name:     "regexps matching head",
+		patterns: []string{"a?", "a?b*c+"},
+		expected: "a?(?:b*c+)?",
+	},
+	{
+		name:     "regexps with same prefix",
+		patterns: []string{"a?b+cd", "a?b+c*", "a?b*c+"},
+		expected: "a?(?:b+(?:cd|c*)|b*c+)",
+	},
+	{
+		name:     "regexps with same prefixes",
+		patterns: []string{"a?b+c*", "a?b+c*d*", "a?b+", "a?"},
+		expected: "a?(?:b+(?:c*d*)?)?",
+	},
+	{
+		name:     "regexps with same prefixes and flags",
+		patterns: []string{"(?i:a*b+c*)", "(?i:a*b+(?-i:c*d*))", "(?i:a*)(?i:b+)", "a*", "A*"},
+		expected: "(?i:A*B+)(?:(?i:C*)|c*d*)|a*|A*",
+	},
+	{
+		name:     "regexps with irrelevant flags",
+		patterns: []string{"(?i:1)a", "1b", "(?s:2)c", "2d", "(?i:[0-9])x", "[0-9]y", "(?U:e+?)", "e+"},
+		expected: "1[ab]|2[cd]|[0-9][xy]|e+",
+	},
+	{
+		name:     "regexps with same prefixes and different flags",
+		patterns: []string{"a?(?i:b+c*)", "(?i:a?)(?i:b+c*d*)", "(?i:a?)b+", "a?"},
+		expected: "a?(?i:(?:B+C*)?)|(?i:A?)(?:(?i:B+C*D*)|b+)",
+	},
+	{
+		name:     "regexps with same literal prefix",
+		patterns: []string{"abcd*e*", "abcde*f*", "abefg?", "ab"},
+		expected: "ab(?:c(?:d*e*|de*f*)|efg?)?",
+	},
+	{
+		name:     "regexps with same suffix",
+		patterns: []string{"ab*c", "c+", "bab?c", "a+c", "cbc+", "dbc+", "ab*c", "c*d+", "d+"},
+		expected: "(?:ab*|bab?|a+)c|(?:[cd]b)?c+|c*d+",
+	},
+	{
+		name:     "regexps with same literal suffix",
+		patterns: []string{"ab*cde", "bcde", "a*de", "cde"},
+		expected: "(?:(?:ab*|b)?c|a*)de",
+	},
+}
 
 func TestJoin(t *testing.T) {
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Join(tc.patterns)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			expected := tc.expected
+			if tc.trie != "" {
+				expected = tc.trie
+			}
+			if got != expected {
+				t.Errorf("expected: %s, got: %s", expected, got)
+			}
+		})
+	}
+	if _, err := Join([]string{"*"}); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestJoinSyntax(t *testing.T) {
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := JoinSyntax(tc.patterns)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			if got := r.String(); got != tc.expected {
+				t.Errorf("expected: %s, got: %s", tc.expected, got)
+			}
+		})
+	}
+	if _, err := JoinSyntax([]string{"*"}); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestCompile(t *testing.T) {
 	testCases := []struct {
 		name     string
 		patterns []string
-		expected string
+		inputs   []string
+		expected []bool
 	}{
 		{
 			name:     "empty",
 			patterns: []string{},
-			expected: "",
+			inputs:   []string{"", "a"},
+			expected: []bool{false, false},
 		},
 		{
 			name:     "empty literal",
 			patterns: []string{""},
-			expected: "(?:)",
-		},
-		{
-			name:     "empty literals",
-			patterns: []string{"", ""},
-			expected: "(?:)",
-		},
-		{
-			name:     "single literal",
-			patterns: []string{"abc"},
-			expected: "abc",
-		},
-		{
-			name:     "single literal with flag",
-			patterns: []string{"(?i:abc)"},
-			expected: "(?i:ABC)",
-		},
-		{
-			name:     "single literal with multiple flags",
-			patterns: []string{"(?ims:^a.b.c$)"},
-			expected: "(?ims:^A.B.C$)",
-		},
-		{
-			name:     "multiple literals",
-			patterns: []string{"abc", "def", "ghi"},
-			expected: "abc|def|ghi",
-		},
-		{
-			name:     "multiple literals with same flag",
-			patterns: []string{"(?i:abc)", "(?i:def)", "(?i:ghi)"},
-			expected: "(?i:ABC|DEF|GHI)",
-		},
-		{
-			name:     "multiple literals with different flags",
-			patterns: []string{"(?i:abc)", "(?m:d.$)", "(?s:^g.i)"},
-			expected: "(?-s:(?i:ABC)|(?m:d.$)|(?ms:^g.i))",
-		},
-		{
-			name:     "multiple characters with different flags",
-			patterns: []string{"a", "b", "(?i:c)?", "d"},
-			expected: "[Ca-d]?",
-		},
-		{
-			name:     "same literals",
-			patterns: []string{"abc", "def", "abc", "def"},
-			expected: "abc|def",
-		},
-		{
-			name:     "same prefixes with different length",
-			patterns: []string{"abc", "ab", "ad", "a"},
-			expected: "a(?:bc?|d)?",
-		},
-		{
-			name:     "same prefixes with different length",
-			patterns: []string{"abcd", "abcf", "abc", "abce", "abcgh", "abdc"},
-			expected: "ab(?:c(?:[d-f]|gh)?|dc)",
-		},
-		{
-			name:     "same prefixes with same length",
-			patterns: []string{"abcde", "abcfg", "abcgh"},
-			expected: "abc(?:de|fg|gh)",
-		},
-		{
-			name:     "same prefixes in increasing length order",
-			patterns: []string{"a", "ab", "abc", "abcd"},
-			expected: "a(?:b(?:cd?)?)?",
-		},
-		{
-			name:     "same prefixes in decreasing length order",
-			patterns: []string{"abcd", "abc", "ab", "a"},
-			expected: "a(?:b(?:cd?)?)?",
-		},
-		{
-			name:     "same prefixes with same flag",
-			patterns: []string{"(?i:abc)", "(?i:ab)", "(?i:ad)", "(?i:a)"},
-			expected: "(?i:A(?:BC?|D)?)",
-		},
-		{
-			name:     "same prefixes with various flags",
-			patterns: []string{"(?i:abc)", "(?:a.*b$)", "(?im:ad$|ae)", "(?sm:a.$)", "(?U:a.*c$)"},
-			expected: "(?-s:(?im:A(?:BC|D$|E))|(?m:a(?:.*b|(?s:.)|.*?c)$))",
-		},
-		{
-			name:     "same prefix and suffix",
-			patterns: []string{"abcdefg", "abcfg", "abefg", "befg", "beefg"},
-			expected: "(?:ab(?:c(?:de)?|e)|bee?)fg",
-		},
-		{
-			name:     "same prefix and suffix with double quests",
-			patterns: []string{"abcd", "abd", "acd", "ad"},
-			expected: "ab?c?d",
-		},
-		{
-			name:     "same prefix and suffix with triple quests",
-			patterns: []string{"abcde", "acde", "abde", "abce", "abe", "ace", "ade", "ae"},
-			expected: "ab?c?d?e",
-		},
-		{
-			name:     "multiple prefix groups",
-			patterns: []string{"abc", "ab", "abcd", "a", "bcd", "bcdef", "cdef", "cdeh"},
-			expected: "a(?:b(?:cd?)?)?|bcd(?:ef)?|cde[fh]",
-		},
-		{
-			name:     "merge literal to quest",
-			patterns: []string{"abc(?:def)?", "abc"},
-			expected: "abc(?:def)?",
-		},
-		{
-			name:     "merge literal to star",
-			patterns: []string{"abc(?:def)*", "abcdef", "abc"},
-			expected: "abc(?:def)*",
-		},
-		{
-			name:     "merge literal to plus",
-			patterns: []string{"abc(?:def)+", "abcdef", "abc"},
-			expected: "abc(?:def)*",
-		},
-		{
-			name:     "merge literal to alternate",
-			patterns: []string{"abc(?:de|f)", "abc"},
-			expected: "abc(?:de|f)?",
+			inputs:   []string{"", "a"},
+			expected: []bool{true, true},
 		},
 		{
-			name:     "merge literal to alternate with plus",
-			patterns: []string{"abc(?:de|[f-h]+)", "abc", "abc"},
-			expected: "abc(?:de|[f-h]*)",
-		},
-		{
-			name:     "merge literal to concat",
-			patterns: []string{"abca*b*", "abc"},
-			expected: "abc(?:a*b*)?",
-		},
-		{
-			name:     "merge literal to concat",
-			patterns: []string{"abca*b*", "abcde"},
-			expected: "abc(?:a*b*|de)",
-		},
-		{
-			name:     "merge literal to alternate in quest",
-			patterns: []string{"abc(?:de|fh)?", "abcff", "abcf", "abchh"},
-			expected: "abc(?:de|f[fh]?|hh)?",
-		},
-		{
-			name:     "merge literal to quest with suffix",
-			patterns: []string{"abc(?:def)?ghi", "abcd"},
-			expected: "abc(?:(?:def)?ghi|d)",
-		},
-		{
-			name:     "merge literal to alternate with same prefix",
-			patterns: []string{"abcfd|def", "abcdef", "abcfe"},
-			expected: "abc(?:f[de]|def)|def",
-		},
-		{
-			name:     "merge literal to alternate with different prefix",
-			patterns: []string{"abc|def", "ghi"},
-			expected: "abc|def|ghi",
-		},
-		{
-			name:     "character class",
-			patterns: []string{"a", "1", "z", "2"},
-			expected: "[12az]",
-		},
-		{
-			name:     "character class with prefix",
-			patterns: []string{"aa", "ab"},
-			expected: "a[ab]",
-		},
-		{
-			name:     "character class in prefix",
-			patterns: []string{"abcde", "abc", "bbcde", "bbc", "cbcde", "cbc"},
-			expected: "[a-c]bc(?:de)?",
-		},
-		{
-			name:     "add character class to a character",
-			patterns: []string{"d?", "[a-c]", "e"},
-			expected: "[a-e]?",
+			name:     "literals",
+			patterns: []string{"^abc$", "^abd$", "^b$"},
+			inputs:   []string{"abc", "abd", "abe", "b", "ab"},
+			expected: []bool{true, true, false, true, false},
 		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			re, err := Compile(tc.patterns)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			for i, input := range tc.inputs {
+				if got := re.MatchString(input); got != tc.expected[i] {
+					t.Errorf("%s: expected: %t, got: %t", input, tc.expected[i], got)
+				}
+			}
+			if got, expected := MustCompile(tc.patterns).String(), re.String(); got != expected {
+				t.Errorf("expected: %s, got: %s", expected, got)
+			}
+		})
+	}
+	if _, err := Compile([]string{"*"}); err == nil {
+		t.Fatalf("expected an error")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic")
+		}
+	}()
+	MustCompile([]string{"*"})
+}
+
+func TestJoinLiterals(t *testing.T) {
+	testCases := []struct {
+		name     string
+		literals []string
+		expected string
+	}{
 		{
-			name:     "add character class to a character class",
-			patterns: []string{"[a-c]", "[e-f]", "d"},
-			expected: "[a-f]",
+			name:     "empty",
+			literals: []string{},
+			expected: "",
 		},
 		{
-			name:     "add complex character class to a complex character class",
-			patterns: []string{"[i-kea-c]", "[f-hd]"},
-			expected: "[a-k]",
+			name:     "empty literal",
+			literals: []string{"", "abc"},
+			expected: "(?:abc)?",
 		},
 		{
-			name:     "add character to character class negation",
-			patterns: []string{"[^0-9]", "3", "5"},
-			expected: "[^0-246-9]",
+			name:     "literals",
+			literals: []string{"abc", "abd", "bcd"},
+			expected: "ab[cd]|bcd",
 		},
 		{
-			name:     "add quest of character to character class negation",
-			patterns: []string{"[^0-9]", "3?", "5"},
-			expected: "[^0-246-9]?",
+			name:     "literals with meta characters",
+			literals: []string{"a.b", "a+b", "a(b", "[a-z]*", "example.com", "example.org"},
+			expected: `a[\(\+\.]b|\[a-z\]\*|example\.(?:com|org)`,
 		},
 		{
-			name:     "add character to character class negation to match anything",
-			patterns: []string{"[^0]", "0"},
-			expected: "(?s:.)",
+			name:     "literals with backslashes",
+			literals: []string{`\d`, `\w`, `\`},
+			expected: `\\[dw]?`,
 		},
 		{
-			name:     "merge literal prefix rather than character class",
-			patterns: []string{"a", "c", "e", "ab", "cd", "ef"},
-			expected: "ab?|cd?|ef?",
+			name:     "literals with invalid utf-8",
+			literals: []string{"a\xff", "a\xfe"},
+			expected: "a\ufffd",
 		},
 		{
-			name:     "add character class to a character class negation",
-			patterns: []string{"[a-d]", "[^c-g]", "f"},
-			expected: "[^eg]",
+			name:     "literals of many edges",
+			literals: alphabetLiterals("ing"),
+			expected: "[a-z]ing",
 		},
 		{
-			name:     "successive character class",
-			patterns: []string{"aa", "ab", "ac"},
-			expected: "a[a-c]",
+			name:     "literals of many edges to different nodes",
+			literals: append(alphabetLiterals("ing"), "ab", "zz", ""),
+			expected: "(?:a(?:ing|b)|[b-y]ing|z(?:ing|z))?",
 		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := JoinLiterals(tc.literals); got != tc.expected {
+				t.Errorf("expected: %s, got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+// alphabetLiterals returns the literals from a to z with the suffix.
+func alphabetLiterals(suffix string) []string {
+	literals := make([]string, 0, 26)
+	for c := 'a'; c <= 'z'; c++ {
+		literals = append(literals, string(c)+suffix)
+	}
+	return literals
+}
+
+func TestJoinWithOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		opts     Options
+		expected string
+		err      string
+	}{
 		{
-			name:     "successive character class in random order",
-			patterns: []string{"ac", "aa", "ae", "ab", "ad"},
-			expected: "a[a-e]",
+			name:     "default flags",
+			patterns: []string{"^abc", `^\d$`},
+			opts:     Options{},
+			expected: "(?m:^(?:abc|[0-9]$))",
 		},
 		{
-			name:     "add case insensitive literal to a literal",
-			patterns: []string{"a", "(?i:b)", "c"},
-			expected: "[Ba-c]",
+			name:     "one line flag",
+			patterns: []string{"^abc", "^abd$"},
+			opts:     Options{Flags: DefaultFlags | syntax.OneLine},
+			expected: "\\Aab(?:c|d\\z)",
 		},
 		{
-			name:     "add case insensitive literal to a character class",
-			patterns: []string{"(?i:a)", "[b-e]", "(?i:f)"},
-			expected: "[AFa-f]",
+			name:     "fold case flag",
+			patterns: []string{"abc", "abd"},
+			opts:     Options{Flags: DefaultFlags | syntax.FoldCase},
+			expected: "(?i:AB[CDcd])",
 		},
 		{
-			name:     "add case insensitive character class to a character class",
-			patterns: []string{"[a-c]", "(?i:[d-f])", "[g-i]"},
-			expected: "[D-Fa-i]",
+			name:     "non-greedy flag",
+			patterns: []string{"a*", "b+", "c*?"},
+			opts:     Options{Flags: DefaultFlags | syntax.NonGreedy},
+			expected: "a*?|b+?|c*",
 		},
 		{
-			name:     "add case insensitive literal to a quest of character class",
-			patterns: []string{"(?i:a)", "[b-e]?", "(?i:f)"},
-			expected: "[AFa-f]?",
+			name:     "posix flags",
+			patterns: []string{"a+b", "a+c"},
+			opts:     Options{POSIX: true},
+			expected: "a+[bc]",
 		},
 		{
-			name:     "numbers",
-			patterns: []string{"1", "9", "2", "6", "3"},
-			expected: "[1-369]",
+			name:     "posix flags with perl syntax",
+			patterns: []string{"a", `\d`},
+			opts:     Options{POSIX: true},
+			err:      "pattern 1: error parsing regexp: invalid escape sequence: `\\d`",
 		},
 		{
-			name:     "numbers 0 to 5",
-			patterns: []string{"0", "4", "3", "5", "1", "2"},
-			expected: "[0-5]",
+			name:     "literal",
+			patterns: []string{"a.b", "a+b", "a(b"},
+			opts:     Options{Literal: true},
+			expected: `a[\(\+\.]b`,
 		},
 		{
-			name:     "numbers 0 to 10",
-			patterns: []string{"1", "9", "2", "6", "3", "7", "10", "8", "0", "5", "4"},
-			expected: "10?|[02-9]",
+			name:     "literal with fold case flag",
+			patterns: []string{"a.b", "A+B"},
+			opts:     Options{Flags: DefaultFlags | syntax.FoldCase, Literal: true},
+			expected: `(?i:A[\+\.]B)`,
 		},
 		{
-			name:     "numbers with prefix",
-			patterns: []string{"a2", "a1", "a0", "a8", "a3", "a5", "a6", "a4", "a7", "a11", "a2", "a9", "a0", "a10"},
-			expected: "a(?:[02-9]|1[01]?)",
+			name:     "anchor line",
+			patterns: []string{"abc", "abd"},
+			opts:     Options{Anchor: AnchorLine},
+			expected: "(?m:^ab[cd]$)",
 		},
 		{
-			name:     "add empty literal to quest",
-			patterns: []string{"abc", "", ""},
-			expected: "(?:abc)?",
+			name:     "anchor text",
+			patterns: []string{"abc", "bcd"},
+			opts:     Options{Anchor: AnchorText},
+			expected: `\A(?:abc|bcd)\z`,
 		},
 		{
-			name:     "add empty literal to plus and star",
-			patterns: []string{"(?:abc)+", "", ""},
-			expected: "(?:abc)*",
+			name:     "anchor word",
+			patterns: []string{"abc", "abd"},
+			opts:     Options{Anchor: AnchorWord},
+			expected: `\bab[cd]\b`,
 		},
 		{
-			name:     "add empty literal to character class",
-			patterns: []string{"[135]", "", "7"},
-			expected: "[1357]?",
+			name:     "anchor text with anchored patterns",
+			patterns: []string{"^foo", `\Abar`, "baz$", `(?:^|\A)qux`},
+			opts:     Options{Anchor: AnchorText},
+			expected: `\A(?:foo|bar|baz|qux)\z`,
 		},
 		{
-			name:     "add empty literal to alternate with quest",
-			patterns: []string{"abc", "b", "", ""},
-			expected: "abc|b?",
+			name:     "anchor line with anchored patterns",
+			patterns: []string{"^foo", `\Abar`, "baz$"},
+			opts:     Options{Anchor: AnchorLine},
+			expected: `(?m:^(?:foo|\Abar|baz)$)`,
 		},
 		{
-			name:     "add empty literal to alternate with plus and star",
-			patterns: []string{"abc", "b+", "", ""},
-			expected: "abc|b*",
+			name:     "anchor word with anchored patterns",
+			patterns: []string{`\bfoo\b`, `\bbar`},
+			opts:     Options{Anchor: AnchorWord},
+			expected: `\b(?:foo|bar)\b`,
 		},
 		{
-			name:     "add literal to empty literal",
-			patterns: []string{"", "abc", ""},
-			expected: "(?:abc)?",
+			name:     "anchor empty pattern",
+			patterns: []string{"^"},
+			opts:     Options{Anchor: AnchorText},
+			expected: `\A\z`,
 		},
 		{
-			name:     "add literal with a flag to empty literal",
-			patterns: []string{"", "(?i:abc)", ""},
-			expected: "(?i:(?:ABC)?)",
+			name:     "anchor without patterns",
+			patterns: []string{},
+			opts:     Options{Anchor: AnchorText},
+			expected: "",
 		},
 		{
-			name:     "add quest to empty literal",
-			patterns: []string{"", "(?:abc)?"},
-			expected: "(?:abc)?",
+			name:     "fold repeats",
+			patterns: []string{"00000000", "000000", "aaaaab", "aaaaa"},
+			opts:     Options{FoldRepeats: true},
+			expected: "0{6}(?:00)?|a{5}b?",
 		},
 		{
-			name:     "add quest to quest",
-			patterns: []string{"(?:abc)?", "(?:abc)?"},
-			expected: "(?:abc)?",
+			name:     "fold repeats with quests",
+			patterns: []string{"000000", "0000000", "00000000", "(?:ab)?(?:ab)?"},
+			opts:     Options{FoldRepeats: true},
+			expected: "0{6,8}|(?:ab){0,2}",
 		},
 		{
-			name:     "add star to empty literal",
-			patterns: []string{"", "(?:abc)*"},
-			expected: "(?:abc)*",
+			name:     "fold repeats of repeats",
+			patterns: []string{"x{2}x{3}y+y+", "x*?x*?", "aa"},
+			opts:     Options{FoldRepeats: true},
+			expected: "x{5}y+y+|x*?x*?|aa",
 		},
 		{
-			name:     "add star and plus to character class",
-			patterns: []string{"a[a-c]c", "a[a-c]*c", "a[a-c]+c", "a[a-d]+c"},
-			expected: "a(?:[a-c]*|[a-d]+)c",
+			name:     "fold repeats within limit",
+			patterns: []string{"0{999}00", "1{998}1"},
+			opts:     Options{FoldRepeats: true},
+			expected: "0{999}00|1{999}",
 		},
 		{
-			name:     "add quest and plus to character class",
-			patterns: []string{"a[a-c]c", "aac", "a[a-c]?c", "a[a-c]+c", "a[a-d]c"},
-			expected: "a(?:[a-c]*|[a-d])c",
+			name:     "merge case",
+			patterns: []string{"foo1", "Foo1", "fOo1", "FOo1", "foO1", "FoO1", "fOO1", "FOO1", "(?i)ab"},
+			opts:     Options{MergeCase: true},
+			expected: "(?i:FOO1|AB)",
 		},
 		{
-			name:     "add quest of character class to literal",
-			patterns: []string{"abc", "a[a-c]?c"},
-			expected: "a[a-c]?c",
+			name:     "merge case of partial variants",
+			patterns: []string{"foo", "Foo", "FOO", "kx", "Kx", "ay", "Ay"},
+			opts:     Options{MergeCase: true},
+			expected: "foo|F(?:oo|OO)|[Kk]x|[Aa]y",
 		},
 		{
-			name:     "add quest of character class to character class",
-			patterns: []string{"abc", "adc", "a[a-c]?c"},
-			expected: "a[a-d]?c",
+			name:     "expand case",
+			patterns: []string{"(?i)foo", "(?i:b)ar", "(?i)k1"},
+			opts:     Options{ExpandCase: true, MergeCase: true},
+			expected: "[Ff][Oo][Oo]|[Bb]ar|[KkK]1",
 		},
 		{
-			name:     "add plus to empty literal",
-			patterns: []string{"", "(?:abc)+"},
-			expected: "(?:abc)*",
+			name:     "canonical literals",
+			patterns: []string{"b", "", "a"},
+			opts:     Options{Literal: true, Canonical: true},
+			expected: "[ab]?",
 		},
 		{
-			name:     "add plus to literal",
-			patterns: []string{"abc", "(?:ab)+", "(?:abc)+"},
-			expected: "(?:abc)+|(?:ab)+",
+			name:     "canonical",
+			patterns: []string{"c", "b|a", "ab", "a"},
+			opts:     Options{Canonical: true},
+			expected: "[a-c]|ab",
 		},
 		{
-			name:     "add plus to quest",
-			patterns: []string{"(?:abc)?", "(?:ab)+", "(?:abc)+"},
-			expected: "(?:abc)*|(?:ab)+",
+			name:     "leftmost first",
+			patterns: []string{"a", "ab", "foo", "bar", "foobar", "fa", "b"},
+			opts:     Options{LeftmostFirst: true},
+			expected: "ab??|f(?:oo(?:bar)??|a)|b(?:ar)?",
 		},
 		{
-			name:     "add character class to empty literal",
-			patterns: []string{"", "[a-c]"},
-			expected: "[a-c]?",
+			name:     "prefer longest",
+			patterns: []string{"a|bc|b*d", "(?:ab)??", "", "cca"},
+			opts:     Options{PreferLongest: true},
+			expected: "b*d|cca|bc|(?:ab)?|a?",
 		},
 		{
-			name:     "add alternate",
-			patterns: []string{"a", "[a-c]|bb", "cc|d"},
-			expected: "[a-d]|bb|cc",
+			name:     "leftmost first with canonical",
+			patterns: []string{"abc", "a", "ab"},
+			opts:     Options{Canonical: true, LeftmostFirst: true},
+			expected: "a(?:bc|(?:)|b)",
 		},
 		{
-			name:     "merge suffix",
-			patterns: []string{"abcde", "cde", "bde"},
-			expected: "(?:(?:ab)?c|b)de",
+			name:     "leftmost first with repeats",
+			patterns: []string{"b", "(a)x", "c{2}x", "d", "e{0,2}", "f"},
+			opts:     Options{LeftmostFirst: true},
+			expected: "[bd]|(a)x|c{2}x|e{0,2}|f",
 		},
 		{
-			name:     "merge suffix in increasing length order",
-			patterns: []string{"e", "de", "cde", "bcde", "abcde"},
-			expected: "(?:(?:(?:a?b)?c)?d)?e",
+			name:     "leftmost first with capture inputs",
+			patterns: []string{"foo", "foobar"},
+			opts:     Options{LeftmostFirst: true, Captures: CaptureInput},
+			expected: "(?P<input0>foo)|(?P<input1>foobar)",
 		},
 		{
-			name:     "merge suffix in decreasing length order",
-			patterns: []string{"abcde", "bcde", "cde", "de", "e"},
-			expected: "(?:(?:(?:a?b)?c)?d)?e",
+			name:     "preserve captures",
+			patterns: []string{"(foo)bar", "(foo)baz", "(a)(foo)bar"},
+			opts:     Options{},
+			expected: "(foo)ba[rz]|(a)(foo)bar",
 		},
 		{
-			name:     "regexps matching head",
-			patterns: []string{"a?", "a?b*c+"},
-			expected: "a?(?:b*c+)?",
+			name:     "strip captures",
+			patterns: []string{"(foo)bar", "(a)(foo)baz", "(?P<x>foo)bax"},
+			opts:     Options{Captures: CaptureStrip},
+			expected: "fooba[rx]|afoobaz",
 		},
 		{
-			name:     "regexps with same prefix",
-			patterns: []string{"a?b+cd", "a?b+c*", "a?b*c+"},
-			expected: "a?(?:b+(?:cd|c*)|b*c+)",
+			name:     "capture inputs",
+			patterns: []string{"(foo)bar", "foobaz|bar", "foobar"},
+			opts:     Options{Captures: CaptureInput},
+			expected: "(?P<input0>(foo)bar)|(?P<input1>foobaz|bar)|(?P<input2>foobar)",
 		},
 		{
-			name:     "regexps with same prefixes",
-			patterns: []string{"a?b+c*", "a?b+c*d*", "a?b+", "a?"},
-			expected: "a?(?:b+(?:c*d*)?)?",
+			name:     "capture inputs with shared prefixes",
+			patterns: []string{"foobar", "foobaz", "foo", "bar"},
+			opts:     Options{Captures: CaptureInput},
+			expected: "foo(?:ba(?:(?P<input0>r)|(?P<input1>z))|(?P<input2>))|(?P<input3>bar)",
 		},
 		{
-			name:     "regexps with same prefixes and flags",
-			patterns: []string{"(?i:a*b+c*)", "(?i:a*b+(?-i:c*d*))", "(?i:a*)(?i:b+)", "a*", "A*"},
-			expected: "A*(?:(?i:B+)(?:(?i:C*)|c*d*))?|a*", // bug in regexp/syntax (golang/go#59007)
+			name:     "capture inputs with names",
+			patterns: []string{"foobar", "foobaz", "foobaq", "(a)b", "(a)c"},
+			opts:     Options{Captures: CaptureInput, Names: []string{"x", "", "x", "rule_3"}},
+			expected: "fooba(?:(?P<x>[qr])|(?P<input1>z))|(a)(?:(?P<rule_3>b)|(?P<input4>c))",
 		},
 		{
-			name:     "regexps with same prefixes and different flags",
-			patterns: []string{"a?(?i:b+c*)", "(?i:a?)(?i:b+c*d*)", "(?i:a?)b+", "a?"},
-			expected: "a?(?i:(?:B+C*)?)|(?i:A?)(?:(?i:B+C*D*)|b+)",
+			name:     "capture inputs with invalid name",
+			patterns: []string{"foo", "bar"},
+			opts:     Options{Captures: CaptureInput, Names: []string{"x", "rule-1"}},
+			err:      "pattern 1: error parsing regexp: invalid named capture: `rule-1`",
 		},
 		{
-			name:     "regexps with same literal prefix",
-			patterns: []string{"abcd*e*", "abcde*f*", "abefg?", "ab"},
-			expected: "ab(?:c(?:d*e*|de*f*)|efg?)?",
+			name:     "capture literals",
+			patterns: []string{"a.b", "a+b"},
+			opts:     Options{Literal: true, Captures: CaptureInput},
+			expected: `a(?:(?P<input0>\.b)|(?P<input1>\+b))`,
 		},
 		{
-			name:     "regexps with same suffix",
-			patterns: []string{"ab*c", "c+", "bab?c", "a+c", "cbc+", "dbc+", "ab*c", "c*d+", "d+"},
-			expected: "(?:ab*|bab?|a+)c|(?:[cd]b)?c+|c*d+",
+			name:     "shorthand classes",
+			patterns: []string{"a1", "a[02-9]", "a[a-f]", "b[^0-9]"},
+			opts:     Options{Shorthand: true},
+			expected: `a[\da-f]|b\D`,
 		},
 		{
-			name:     "regexps with same literal suffix",
-			patterns: []string{"ab*cde", "bcde", "a*de", "cde"},
-			expected: "(?:(?:ab*|b)?c|a*)de",
+			name:     "unknown dialect",
+			patterns: []string{"abc"},
+			opts:     Options{Dialect: -1},
+			err:      "unknown dialect: -1",
 		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := Join(tc.patterns)
+			got, err := JoinWithOptions(tc.patterns, tc.opts)
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("expected an error but got: %s", got)
+				}
+				if err.Error() != tc.err {
+					t.Errorf("expected error: %s, got: %s", tc.err, err)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatalf("got an error: %s", err)
 			}
@@ -440,7 +941,33 @@ func TestJoin(t *testing.T) {
 			}
 		})
 	}
-	if _, err := Join([]string{"*"}); err == nil {
-		t.Fatalf("expected an error")
+}
+
+func TestJoinCanonical(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	opts := Options{Canonical: true}
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := JoinWithOptions(tc.patterns, opts)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			if err := Verify(tc.patterns, expected); err != nil {
+				t.Fatalf("%s: %s", expected, err)
+			}
+			patterns := slices.Clone(tc.patterns)
+			for range 5 {
+				rnd.Shuffle(len(patterns), func(i, j int) {
+					patterns[i], patterns[j] = patterns[j], patterns[i]
+				})
+				got, err := JoinWithOptions(patterns, opts)
+				if err != nil {
+					t.Fatalf("got an error: %s", err)
+				}
+				if got != expected {
+					t.Errorf("%q: expected: %s, got: %s", patterns, expected, got)
+				}
+			}
+		})
 	}
 }
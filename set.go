@@ -0,0 +1,71 @@
+package rassemble
+
+import (
+	"regexp"
+	"regexp/syntax"
+)
+
+// Set is a set of patterns, which reports the patterns matching a string.
+// The assembled regular expression rejects the strings quickly, and the
+// patterns are tested one by one only when it matches.
+type Set struct {
+	re  *regexp.Regexp
+	res []*regexp.Regexp
+	ids []int
+}
+
+// NewSet assembles the patterns into a set.
+func NewSet(patterns []string) (*Set, error) {
+	var a Assembler
+	for _, pattern := range patterns {
+		if err := a.Add(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return a.Set()
+}
+
+// Set builds a set of the patterns in the assembler. The indices
+// are counted in the same way as PatternError.
+func (a *Assembler) Set() (*Set, error) {
+	re, err := compile(a.Regexp())
+	if err != nil {
+		return nil, err
+	}
+	s := &Set{re: re}
+	for i := 0; i < len(a.units); {
+		j, sub := i, []*syntax.Regexp(nil)
+		for ; j < len(a.units) && a.units[j].id == a.units[i].id; j++ {
+			sub = append(sub, a.units[j].re)
+		}
+		r := sub[0]
+		if len(sub) > 1 {
+			r = &syntax.Regexp{Op: syntax.OpAlternate, Sub: sub}
+		}
+		re, err := compile(a.options().Anchor.wrap(r))
+		if err != nil {
+			return nil, err
+		}
+		s.res, s.ids, i = append(s.res, re), append(s.ids, a.units[i].id), j
+	}
+	return s, nil
+}
+
+// Match returns the sorted indices of the patterns matching the string.
+func (s *Set) Match(str string) []int {
+	if !s.re.MatchString(str) {
+		return nil
+	}
+	var ids []int
+	for i, re := range s.res {
+		if re.MatchString(str) {
+			ids = append(ids, s.ids[i])
+		}
+	}
+	return ids
+}
+
+// MatchString reports whether any of the patterns matches the string.
+func (s *Set) MatchString(str string) bool {
+	return s.re.MatchString(str)
+}
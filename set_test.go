@@ -0,0 +1,107 @@
+package rassemble
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"testing"
+	"unicode"
+)
+
+func TestSet(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		inputs   map[string][]int
+	}{
+		{
+			name:     "empty",
+			patterns: []string{},
+			inputs:   map[string][]int{"": nil, "a": nil},
+		},
+		{
+			name:     "literals",
+			patterns: []string{"abc", "abd", "bd", "b"},
+			inputs: map[string][]int{
+				"":    nil,
+				"abc": {0, 3},
+				"abd": {1, 2, 3},
+				"xbx": {3},
+				"xyz": nil,
+			},
+		},
+		{
+			name:     "alternatives",
+			patterns: []string{"^a|b$", "^(?:x+|a)", "c", "^a|b$"},
+			inputs: map[string][]int{
+				"ab":  {0, 1, 3},
+				"ba":  nil,
+				"xxb": {0, 1, 3},
+				"c":   {2},
+			},
+		},
+		{
+			name:     "empty pattern",
+			patterns: []string{"a", ""},
+			inputs:   map[string][]int{"": {1}, "a": {0, 1}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewSet(tc.patterns)
+			if err != nil {
+				t.Fatalf("got an error: %s", err)
+			}
+			for input, expected := range tc.inputs {
+				if got := s.Match(input); fmt.Sprint(got) != fmt.Sprint(expected) {
+					t.Errorf("%q: expected: %v, got: %v", input, expected, got)
+				}
+				if got := s.MatchString(input); got != (expected != nil) {
+					t.Errorf("%q: expected: %t, got: %t", input, !got, got)
+				}
+			}
+		})
+	}
+	if _, err := NewSet([]string{"a", "("}); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestAssemblerSet(t *testing.T) {
+	a := NewAssembler(Options{Literal: true, Anchor: AnchorText})
+	for _, literal := range []string{"a.c", "abc", "a", "abc"} {
+		_ = a.Add(literal)
+	}
+	a.Remove("a")
+	s, err := a.Set()
+	if err != nil {
+		t.Fatalf("got an error: %s", err)
+	}
+	for input, expected := range map[string][]int{
+		"a.c": {0}, "abc": {1, 3}, "a": nil, "xabc": nil,
+	} {
+		if got := s.Match(input); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("%q: expected: %v, got: %v", input, expected, got)
+		}
+	}
+}
+
+func TestAssemblerSetError(t *testing.T) {
+	// a{1001} exceeds the maximum repetition count
+	repeat := &syntax.Regexp{Op: syntax.OpRepeat, Min: 1001, Max: 1001,
+		Sub: []*syntax.Regexp{{Op: syntax.OpLiteral, Rune: []rune("a")}}}
+	// the unmerged ranges are printed as [^a-`b-a], but fixed by merging x
+	class := &syntax.Regexp{Op: syntax.OpCharClass,
+		Rune: []rune{0, '`', 'a', 'a', 'b', unicode.MaxRune}}
+	for _, rs := range [][]*syntax.Regexp{
+		{repeat},
+		{class, {Op: syntax.OpLiteral, Rune: []rune("x")}},
+	} {
+		var a Assembler
+		for _, r := range rs {
+			a.AddRegexp(r)
+		}
+		if _, err := a.Set(); err == nil {
+			t.Errorf("expected an error: %s", a.String())
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package rassemble
+
+import (
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"sync"
+	"unicode"
+)
+
+// RenderShorthand renders the regular expression in the dialect like Render,
+// spelling the character classes with the shorthands like \d, \w and \pL,
+// which are used only where the dialect gives them the same meaning.
+func RenderShorthand(r *syntax.Regexp, d Dialect) (string, error) {
+	return render(r, d, true)
+}
+
+type namedClass struct {
+	name, negated string
+	rs            []rune
+	group         string // major category of the Unicode categories
+}
+
+// The classes of the Perl shorthands in Go. In PCRE, JavaScript and Java,
+// \d and \w are the same, but \s also matches \v and some more.
+var perlClasses = []namedClass{
+	{`\d`, `\D`, []rune{'0', '9'}, ""},
+	{`\w`, `\W`, []rune{'0', '9', 'A', 'Z', '_', '_', 'a', 'z'}, ""},
+	{`\s`, `\S`, []rune{'\t', '\n', '\f', '\r', ' ', ' '}, ""},
+}
+
+// The Unicode tables are the same as the parser only in Go.
+var goClasses = sync.OnceValue(func() []namedClass {
+	cs := slices.Clone(perlClasses)
+	for _, tables := range []map[string]*unicode.RangeTable{unicode.Categories, unicode.Scripts} {
+		names := make([]string, 0, len(tables))
+		for name := range tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c := namedClass{name: `\p{` + name + `}`, negated: `\P{` + name + `}`, rs: tableClass(tables[name])}
+			if len(name) == 1 {
+				c.name, c.negated = `\p`+name, `\P`+name
+			} else if tables[name] == unicode.Categories[name] {
+				c.group = name[:1]
+			}
+			cs = append(cs, c)
+		}
+	}
+	return cs
+})
+
+func (w *renderer) namedClasses() []namedClass {
+	switch w.dialect {
+	case DialectGo:
+		return goClasses()
+	case DialectPCRE, DialectJavaScript, DialectJava:
+		return perlClasses[:2]
+	default:
+		return nil
+	}
+}
+
+// shorthandClass renders the class with the shorthands it contains. The
+// shorthands are chosen one at a time as long as the class gets shorter,
+// or two of a major category, since \p{Lu} and \p{Ll} only help together.
+// No shorthand contains the maximum rune, so such a class is skipped.
+//
+//	[0-9a-f] => [\da-f]
+func (w *renderer) shorthandClass(rs []rune, negated bool) bool {
+	if rs[len(rs)-1] == unicode.MaxRune {
+		return false
+	}
+	rs = normalizeClass(rs)
+	var cs []namedClass
+	for _, c := range w.namedClasses() {
+		if subClass(c.rs, rs) {
+			cs = append(cs, c)
+		}
+	}
+	var names []namedClass
+	var covered []rune
+	for n := w.rangesLen(rs); ; {
+		var best []namedClass
+		for i, c := range cs {
+			for j := i; j < len(cs); j++ {
+				next := []namedClass{c}
+				if j > i {
+					if c.group == "" || c.group != cs[j].group {
+						continue
+					}
+					next = append(next, cs[j])
+				}
+				m := w.rangesLen(trimClass(rs, coverClass(covered, next)))
+				for _, c := range append(names, next...) {
+					m += len(c.name)
+				}
+				if m < n {
+					best, n = next, m
+				}
+			}
+		}
+		if best == nil {
+			break
+		}
+		names, covered = append(names, best...), coverClass(covered, best)
+	}
+	if len(names) == 0 {
+		return false
+	}
+	rest := trimClass(rs, covered)
+	if len(names) == 1 && len(rest) == 0 {
+		if negated {
+			w.WriteString(names[0].negated)
+		} else {
+			w.WriteString(names[0].name)
+		}
+		return true
+	}
+	w.WriteString(`[`)
+	if negated {
+		w.WriteString(`^`)
+	}
+	for _, c := range names {
+		w.WriteString(c.name)
+	}
+	w.ranges(rest)
+	w.WriteString(`]`)
+	return true
+}
+
+func coverClass(rs []rune, cs []namedClass) []rune {
+	for _, c := range cs {
+		rs = append(rs[:len(rs):len(rs)], c.rs...)
+	}
+	return normalizeClass(rs)
+}
+
+// trimClass trims the ranges of the normalized class to the runes not
+// covered by the shorthands. The covered runes between them can be kept.
+//
+//	[a-z] with [aeiou] => [b-z]
+func trimClass(rs, covered []rune) []rune {
+	var zs []rune
+	for i := 0; i < len(rs); i += 2 {
+		for len(covered) > 0 && covered[1] < rs[i] {
+			covered = covered[2:]
+		}
+		if xs := diffClass(rs[i:i+2], covered); len(xs) > 0 {
+			zs = append(zs, xs[0], xs[len(xs)-1])
+		}
+	}
+	return zs
+}
+
+func (w *renderer) rangesLen(rs []rune) int {
+	x := &renderer{dialect: w.dialect}
+	x.ranges(rs)
+	return x.Len()
+}
+
+func tableClass(t *unicode.RangeTable) []rune {
+	var rs []rune
+	for _, r := range t.R16 {
+		rs = appendRange(rs, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range t.R32 {
+		rs = appendRange(rs, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return normalizeClass(rs)
+}
+
+func appendRange(rs []rune, lo, hi, stride rune) []rune {
+	if stride == 1 {
+		return append(rs, lo, hi)
+	}
+	for r := lo; r <= hi; r += stride {
+		rs = append(rs, r, r)
+	}
+	return rs
+}
+
+// normalizeClass sorts the ranges and merges the adjacent ones.
+// Unlike charClass, [ab] is merged into [a-b] to compare the classes.
+func normalizeClass(rs []rune) []rune {
+	rs = slices.Clone(rs)
+	sort.Sort(charClassSlice(rs))
+	var i int
+	for j := 2; j < len(rs); j += 2 {
+		if rs[i+1]+1 >= rs[j] {
+			rs[i+1] = max(rs[i+1], rs[j+1])
+			continue
+		}
+		if i += 2; i != j {
+			rs[i], rs[i+1] = rs[j], rs[j+1]
+		}
+	}
+	return rs[:min(i+2, len(rs))]
+}
+
+// subClass reports whether the normalized class xs is a subset of ys.
+func subClass(xs, ys []rune) bool {
+	for i := 0; i < len(xs); i += 2 {
+		for len(ys) > 0 && ys[1] < xs[i] {
+			ys = ys[2:]
+		}
+		if len(ys) == 0 || xs[i] < ys[0] || ys[1] < xs[i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffClass returns the normalized class of xs excluding ys.
+func diffClass(xs, ys []rune) []rune {
+	var zs []rune
+	for i := 0; i < len(xs); i += 2 {
+		lo, hi := xs[i], xs[i+1]
+		for j := 0; j < len(ys) && ys[j] <= hi; j += 2 {
+			if lo < ys[j] {
+				zs = append(zs, lo, ys[j]-1)
+			}
+			lo = max(lo, ys[j+1]+1)
+		}
+		if lo <= hi {
+			zs = append(zs, lo, hi)
+		}
+	}
+	return zs
+}
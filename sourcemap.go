@@ -0,0 +1,166 @@
+package rassemble
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"slices"
+)
+
+// SourceMap maps the nodes of an assembled regular expression
+// to the indices of the patterns they derive from.
+type SourceMap struct {
+	// Regexp is the assembled regular expression, the same as Assembler.Regexp.
+	Regexp *syntax.Regexp
+	src    map[*syntax.Regexp][]int
+	anchor Anchor
+	units  []unit
+}
+
+// SourceMap assembles the patterns recording where each node derives from.
+// The indices are counted in the same way as PatternError, so they are the
+// indices of the patterns passed to JoinWithOptions. Use Match to trace
+// a match back to the patterns.
+func (a *Assembler) SourceMap() *SourceMap {
+	m := &merger{src: make(map[*syntax.Regexp][]int), ordered: a.options().LeftmostFirst}
+	return &SourceMap{
+		Regexp: a.replay(m), src: m.src,
+		anchor: a.options().Anchor, units: slices.Clone(a.units),
+	}
+}
+
+// Sources returns the sorted indices of the patterns the node derives from.
+// The node should be in the tree of the Regexp field.
+func (s *SourceMap) Sources(r *syntax.Regexp) []int {
+	return (&merger{src: s.src}).sources(r)
+}
+
+// Match returns the sorted indices of the patterns the leftmost match in the
+// string derives from. The patterns of the top-level alternative matching
+// first are tested one by one, whether they match at the same position.
+// The patterns are compiled on each call, so this is for tracing a match.
+func (s *SourceMap) Match(str string) []int {
+	if len(s.units) == 0 {
+		return nil
+	}
+	pos := index(s.Regexp, str)
+	if pos < 0 {
+		return nil
+	}
+	var ids, matched []int
+	alts, rs := alternatives(s.Regexp)
+	for i, r := range rs {
+		// the alternatives never match before the whole
+		if index(r, str) == pos {
+			ids = s.Sources(alts[i])
+			break
+		}
+	}
+	for _, id := range ids {
+		var sub []*syntax.Regexp
+		for _, u := range s.units {
+			if u.id == id {
+				sub = append(sub, u.re)
+			}
+		}
+		if index(s.anchor.wrap(&syntax.Regexp{Op: syntax.OpAlternate, Sub: sub}), str) == pos {
+			matched = append(matched, id)
+		}
+	}
+	return matched
+}
+
+// alternatives returns the top-level alternatives of the pattern, and the
+// patterns with each of them in place of the alternation.
+//
+//	\A(?:foo|bar)\z => foo, bar and \Afoo\z, \Abar\z
+func alternatives(r *syntax.Regexp) ([]*syntax.Regexp, []*syntax.Regexp) {
+	if r.Op == syntax.OpAlternate {
+		return r.Sub, r.Sub
+	}
+	k := -1
+	if r.Op == syntax.OpConcat {
+		for i, rr := range r.Sub {
+			if rr.Op == syntax.OpAlternate && k == -1 {
+				k = i
+			} else if rr.Op < syntax.OpBeginLine || rr.Op > syntax.OpNoWordBoundary {
+				k = -2
+			}
+		}
+	}
+	if k < 0 {
+		return []*syntax.Regexp{r}, []*syntax.Regexp{r}
+	}
+	rs := make([]*syntax.Regexp, len(r.Sub[k].Sub))
+	for i, rr := range r.Sub[k].Sub {
+		sub := slices.Clone(r.Sub)
+		sub[k] = rr
+		rs[i] = &syntax.Regexp{Op: syntax.OpConcat, Sub: sub}
+	}
+	return r.Sub[k].Sub, rs
+}
+
+// index returns the position of the leftmost match of the pattern.
+func index(r *syntax.Regexp, str string) int {
+	// the parts of a valid pattern are valid
+	if loc := regexp.MustCompile(r.String()).FindStringIndex(str); loc != nil {
+		return loc[0]
+	}
+	return -1
+}
+
+func (m *merger) mark(r *syntax.Regexp, id int) {
+	m.src[r] = []int{id}
+	for _, rr := range r.Sub {
+		m.mark(rr, id)
+	}
+}
+
+// absorb records the sources of r2 to r1, where r2 is dropped being equal to r1.
+func (m *merger) absorb(r1, r2 *syntax.Regexp) {
+	if m.src == nil || r1 == r2 {
+		return
+	}
+	m.src[r1] = union(m.src[r1], m.src[r2])
+	for i, rr := range r1.Sub {
+		m.absorb(rr, r2.Sub[i])
+	}
+}
+
+// derive records the sources of rs to r, where r is built from rs.
+func (m *merger) derive(r *syntax.Regexp, rs ...*syntax.Regexp) *syntax.Regexp {
+	if m.src != nil {
+		for _, rr := range rs {
+			m.src[r] = union(m.src[r], m.sources(rr))
+		}
+	}
+	return r
+}
+
+func (m *merger) sources(r *syntax.Regexp) []int {
+	xs := m.src[r]
+	for _, rr := range r.Sub {
+		xs = union(xs, m.sources(rr))
+	}
+	return xs
+}
+
+func union(xs, ys []int) []int {
+	if len(ys) == 0 {
+		return xs
+	}
+	if len(xs) == 0 {
+		return ys
+	}
+	zs := make([]int, 0, len(xs)+len(ys))
+	for len(xs) > 0 && len(ys) > 0 {
+		switch {
+		case xs[0] < ys[0]:
+			zs, xs = append(zs, xs[0]), xs[1:]
+		case xs[0] > ys[0]:
+			zs, ys = append(zs, ys[0]), ys[1:]
+		default:
+			zs, xs, ys = append(zs, xs[0]), xs[1:], ys[1:]
+		}
+	}
+	return append(append(zs, xs...), ys...)
+}
@@ -0,0 +1,178 @@
+package rassemble
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"slices"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			patterns: []string{},
+			expected: []string{},
+		},
+		{
+			name:     "literals",
+			patterns: []string{"abc", "abd", "bd", "", "b", "bcd"},
+			expected: []string{"ab[cd] [0 1]", "b(?:c?d)? [2 4 5]", "(?:) [3]"},
+		},
+		{
+			name:     "nested alternatives",
+			patterns: []string{"abc", "ab", "acbd", "abe"},
+			expected: []string{"b[ce]? [0 1 3]", "cbd [2]"},
+		},
+		{
+			name:     "same patterns",
+			patterns: []string{"foo", "bar", "foo|baz"},
+			expected: []string{"foo [0 2]", "ba[rz] [1 2]"},
+		},
+		{
+			name:     "merge suffix",
+			patterns: []string{"abx", "cx", "dy", "x"},
+			expected: []string{"(?:ab|c)?x [0 1 3]", "dy [2]", "ab [0]", "c [1]"},
+		},
+		{
+			name:     "anchor",
+			patterns: []string{"^abc", "^abd$", "^bc", "xyz"},
+			expected: []string{
+				"(?m:^(?:ab(?:c|d$)|bc)) [0 1 2]", "xyz [3]",
+				"(?m:ab(?:c|d$)) [0 1]", "bc [2]", "c [0]", "(?m:d$) [1]",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var a Assembler
+			for _, pattern := range tc.patterns {
+				if err := a.Add(pattern); err != nil {
+					t.Fatalf("got an error: %s", err)
+				}
+			}
+			s := a.SourceMap()
+			if got, expected := s.Regexp.String(), a.String(); got != expected {
+				t.Errorf("expected: %s, got: %s", expected, got)
+			}
+			got := []string{}
+			var walk func(*syntax.Regexp)
+			walk = func(r *syntax.Regexp) {
+				if r.Op == syntax.OpAlternate {
+					for _, r := range r.Sub {
+						got = append(got, fmt.Sprint(r, " ", s.Sources(r)))
+					}
+				}
+				for _, r := range r.Sub {
+					walk(r)
+				}
+			}
+			walk(s.Regexp)
+			if fmt.Sprint(got) != fmt.Sprint(tc.expected) {
+				t.Errorf("expected: %q, got: %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSourceMapMatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		opts     Options
+		inputs   map[string][]int
+	}{
+		{
+			name:     "empty",
+			patterns: []string{},
+			inputs:   map[string][]int{"": nil, "a": nil},
+		},
+		{
+			name:     "literals",
+			patterns: []string{"abc", "abd", "ab", "bd", "b"},
+			inputs: map[string][]int{
+				"":      nil,
+				"xabcx": {0, 2},
+				"abd":   {1, 2},
+				"xbd":   {3, 4},
+				"bab":   {4},
+				"xyz":   nil,
+			},
+		},
+		{
+			name:     "alternatives",
+			patterns: []string{"^x|b", "a", "b|c"},
+			inputs: map[string][]int{
+				"xb": {0},
+				"cb": {2},
+				"ab": {1},
+				"yb": {0, 2},
+			},
+		},
+		{
+			name:     "anchor",
+			patterns: []string{"a", "ab", "b"},
+			opts:     Options{Anchor: AnchorText},
+			inputs: map[string][]int{
+				"a":  {0},
+				"ab": {1},
+				"b":  {2},
+				"ba": nil,
+			},
+		},
+		{
+			name:     "anchor without alternatives",
+			patterns: []string{"abc", "abd"},
+			opts:     Options{Anchor: AnchorText},
+			inputs: map[string][]int{
+				"abc": {0},
+				"abd": {1},
+				"ab":  nil,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAssembler(tc.opts)
+			for _, pattern := range tc.patterns {
+				if err := a.Add(pattern); err != nil {
+					t.Fatalf("got an error: %s", err)
+				}
+			}
+			s := a.SourceMap()
+			for input, expected := range tc.inputs {
+				if got := s.Match(input); fmt.Sprint(got) != fmt.Sprint(expected) {
+					t.Errorf("%q: expected: %v, got: %v", input, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSourceMapJoin(t *testing.T) {
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAssembler(Options{Anchor: AnchorText})
+			for _, pattern := range tc.patterns {
+				if err := a.Add(pattern); err != nil {
+					t.Fatalf("got an error: %s", err)
+				}
+			}
+			s := a.SourceMap()
+			if got, expected := s.Regexp.String(), a.String(); got != expected {
+				t.Errorf("expected: %s, got: %s", expected, got)
+			}
+			expected := make([]int, len(tc.patterns))
+			for i := range expected {
+				expected[i] = i
+			}
+			if got := s.Sources(s.Regexp); !slices.Equal(got, expected) {
+				t.Errorf("expected: %v, got: %v", expected, got)
+			}
+		})
+	}
+}
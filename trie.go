@@ -0,0 +1,191 @@
+package rassemble
+
+import (
+	"encoding/binary"
+	"regexp/syntax"
+	"slices"
+)
+
+// trie is a node of the trie of the literals. The edges are kept in the
+// order of the insertion to assemble in the same order as the Assembler.
+// The index is built only for the nodes of many edges.
+type trie struct {
+	end   bool
+	runes []rune
+	nexts []*trie
+	index map[rune]int
+}
+
+const trieIndexSize = 16
+
+func (t *trie) insert(rs []rune) {
+	for _, r := range rs {
+		t = t.next(r)
+	}
+	t.end = true
+}
+
+func (t *trie) next(r rune) *trie {
+	if t.index != nil {
+		if i, ok := t.index[r]; ok {
+			return t.nexts[i]
+		}
+		t.index[r] = len(t.runes)
+	} else {
+		for i, x := range t.runes {
+			if x == r {
+				return t.nexts[i]
+			}
+		}
+		if len(t.runes) == trieIndexSize {
+			t.index = make(map[rune]int, 2*trieIndexSize)
+			for i, x := range t.runes {
+				t.index[x] = i
+			}
+			t.index[r] = len(t.runes)
+		}
+	}
+	next := &trie{}
+	t.runes, t.nexts = append(t.runes, r), append(t.nexts, next)
+	return next
+}
+
+// minimize merges the nodes of the same suffixes, which makes the trie
+// the minimal acyclic automaton (DAWG) of the literals.
+func (t *trie) minimize(nodes map[string]*trie, ids map[*trie]int) *trie {
+	key := make([]byte, 0, 1+len(t.runes)*8)
+	if t.end {
+		key = append(key, '$')
+	}
+	for i, r := range t.runes {
+		t.nexts[i] = t.nexts[i].minimize(nodes, ids)
+		key = binary.AppendUvarint(key, uint64(r))
+		key = binary.AppendUvarint(key, uint64(ids[t.nexts[i]]))
+	}
+	if u, ok := nodes[string(key)]; ok {
+		return u
+	}
+	nodes[string(key)], ids[t] = t, len(ids)
+	return t
+}
+
+// groups returns the classes of the edges to each node, in the order.
+func (t *trie) groups() ([][]rune, []*trie) {
+	var runes [][]rune
+	var nexts []*trie
+	var index map[*trie]int
+	if len(t.nexts) > trieIndexSize {
+		index = make(map[*trie]int)
+	}
+	for i, next := range t.nexts {
+		j := -1
+		if index != nil {
+			if k, ok := index[next]; ok {
+				j = k
+			}
+		} else {
+			j = slices.Index(nexts, next)
+		}
+		if j < 0 {
+			j = len(nexts)
+			if index != nil {
+				index[next] = j
+			}
+			runes, nexts = append(runes, nil), append(nexts, next)
+		}
+		runes[j] = append(runes[j], t.runes[i], t.runes[i])
+	}
+	return runes, nexts
+}
+
+// regexp builds the pattern of the trie, merging the edges to the same node
+// into a character class.
+//
+//	abc|abd|bd => ab[cd]|bd
+//	ad|bd|cde => [ab]d|cde
+func (t *trie) regexp() *syntax.Regexp {
+	runes, nexts := t.groups()
+	sub := make([]*syntax.Regexp, len(nexts))
+	for i, next := range nexts {
+		rs := []*syntax.Regexp{trieLabel(runes[i])}
+		for !next.end && len(next.nexts) > 0 {
+			runes, nexts := next.groups()
+			if len(nexts) > 1 {
+				break
+			}
+			rs, next = append(rs, trieLabel(runes[0])), nexts[0]
+		}
+		if len(next.nexts) > 0 {
+			rs = append(rs, next.regexp())
+		}
+		sub[i] = concat(rs...)
+	}
+	var r *syntax.Regexp
+	switch len(sub) {
+	case 0:
+		return &syntax.Regexp{Op: syntax.OpEmptyMatch}
+	case 1:
+		r = sub[0]
+	default:
+		r = &syntax.Regexp{Op: syntax.OpAlternate, Sub: sub}
+	}
+	if t.end {
+		// abc|(?:) => (?:abc)?
+		r = quest(r)
+	}
+	return r
+}
+
+func trieLabel(rs []rune) *syntax.Regexp {
+	if len(rs) == 2 {
+		return &syntax.Regexp{Op: syntax.OpLiteral, Rune: rs[:1]}
+	}
+	return charClass(rs)
+}
+
+// joinLiterals assembles the literals through the trie, which scales to
+// a large number of literals. The patterns are the literals with Literal,
+// or the patterns parsed into literals. It reports false if the options
+// need the Assembler. The result may differ from the Assembler but is
+// equivalent.
+func joinLiterals(patterns []string, opts *Options) (*syntax.Regexp, bool) {
+	if opts.Captures == CaptureInput || opts.Canonical || opts.LeftmostFirst || len(patterns) == 0 {
+		return nil, false
+	}
+	a := NewAssembler(*opts)
+	flags := a.options().Flags
+	if flags&syntax.FoldCase != 0 {
+		return nil, false
+	}
+	var t trie
+	for _, pattern := range patterns {
+		rs := []rune(pattern)
+		if !opts.Literal {
+			var ok bool
+			if rs, ok = parseLiteral(pattern, flags); !ok {
+				return nil, false
+			}
+		}
+		t.insert(rs)
+	}
+	root := t.minimize(make(map[string]*trie), make(map[*trie]int))
+	return a.assemble(&a.m, []*syntax.Regexp{root.regexp()}), true
+}
+
+// parseLiteral parses the pattern and returns the runes if it is a literal.
+//
+//	a\.b => a.b
+func parseLiteral(pattern string, flags syntax.Flags) ([]rune, bool) {
+	r, err := syntax.Parse(pattern, flags)
+	if err != nil {
+		return nil, false
+	}
+	switch r.Op {
+	case syntax.OpEmptyMatch:
+		return nil, true
+	case syntax.OpLiteral:
+		return r.Rune, r.Flags&syntax.FoldCase == 0
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,71 @@
+package rassemble
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestJoinLiteralsRandom(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		literals := make([]string, 1+rnd.IntN(8))
+		patterns := make([]string, len(literals))
+		for i := range literals {
+			var sb strings.Builder
+			for range rnd.IntN(5) {
+				sb.WriteRune([]rune("abc.é\n")[rnd.IntN(6)])
+			}
+			literals[i], patterns[i] = sb.String(), regexp.QuoteMeta(sb.String())
+		}
+		got := JoinLiterals(literals)
+		if err := Verify(patterns, got); err != nil {
+			t.Fatalf("literals: %q, assembled: %s: %s", literals, got, err)
+		}
+	}
+}
+
+// BenchmarkJoinLiterals compares JoinLiterals with Join, which parses the
+// patterns into literals, and the Assembler.
+func BenchmarkJoinLiterals(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		literals := benchmarkLiterals(n)
+		b.Run(fmt.Sprint("JoinLiterals/", n), func(b *testing.B) {
+			for range b.N {
+				JoinLiterals(literals)
+			}
+		})
+		b.Run(fmt.Sprint("Join/", n), func(b *testing.B) {
+			for range b.N {
+				_, _ = Join(literals)
+			}
+		})
+		b.Run(fmt.Sprint("Assembler/", n), func(b *testing.B) {
+			for range b.N {
+				a := NewAssembler(Options{Literal: true})
+				for _, literal := range literals {
+					_ = a.Add(literal)
+				}
+				_ = a.String()
+			}
+		})
+	}
+}
+
+// benchmarkLiterals generates the words with the common suffixes.
+func benchmarkLiterals(n int) []string {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	suffixes := []string{"", "s", "ed", "ing", "tion"}
+	literals := make([]string, n)
+	for i := range literals {
+		var sb strings.Builder
+		for range 2 + rnd.IntN(6) {
+			sb.WriteByte(byte('a' + rnd.IntN(26)))
+		}
+		sb.WriteString(suffixes[rnd.IntN(len(suffixes))])
+		literals[i] = sb.String()
+	}
+	return literals
+}
@@ -0,0 +1,247 @@
+package rassemble
+
+import (
+	"errors"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrTooManyStates is the error of Verify when the automaton gets too large.
+var ErrTooManyStates = errors.New("too many states to verify")
+
+const maxVerifyStates = 1 << 16
+
+// VerifyError is the error of Verify reporting a counterexample.
+type VerifyError struct {
+	Text      string // the string matched by only one side
+	Before    string // the character before the string, empty for the beginning
+	After     string // the character after the string, empty for the end
+	Assembled bool   // whether the assembled pattern matches the string
+}
+
+func (err *VerifyError) Error() string {
+	s := strconv.Quote(err.Text)
+	switch {
+	case err.Before != "" && err.After != "":
+		s += " between " + strconv.Quote(err.Before) + " and " + strconv.Quote(err.After)
+	case err.Before != "":
+		s += " after " + strconv.Quote(err.Before)
+	case err.After != "":
+		s += " before " + strconv.Quote(err.After)
+	}
+	if err.Assembled {
+		return "the assembled pattern matches " + s + " but the patterns do not"
+	}
+	return "the patterns match " + s + " but the assembled pattern does not"
+}
+
+// Verify checks that the assembled pattern matches exactly the strings that
+// any of the patterns matches, parsing them in the same way as Join. The
+// strings are compared along with the characters around them, which matter
+// for the assertions like \b. The error is of type *VerifyError with the
+// shortest counterexample, or ErrTooManyStates. An empty assembled pattern
+// of no patterns is regarded as matching nothing, as in Compile.
+func Verify(patterns []string, assembled string) error {
+	sub := make([]*syntax.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		r, err := syntax.Parse(pattern, DefaultFlags)
+		if err != nil {
+			return &PatternError{i, pattern, err.(*syntax.Error)}
+		}
+		sub[i] = r
+	}
+	if len(sub) == 0 && assembled == "" {
+		return nil
+	}
+	r, err := syntax.Parse(assembled, DefaultFlags)
+	if err != nil {
+		return err
+	}
+	return verify(&syntax.Regexp{Op: syntax.OpAlternate, Sub: sub}, r)
+}
+
+type verifyState struct {
+	pcs    [2][]uint32
+	prev   rune // the kind of the previous character, see contextRune
+	parent int
+	r      rune
+}
+
+// verify explores the product of the subset constructions of the programs.
+// The characters are grouped into the ranges the programs do not tell apart.
+func verify(r1, r2 *syntax.Regexp) error {
+	var progs [2]*syntax.Prog
+	for i, r := range []*syntax.Regexp{r1, r2} {
+		if r.Op == syntax.OpAlternate && len(r.Sub) == 0 {
+			r = &syntax.Regexp{Op: syntax.OpNoMatch}
+		}
+		// Compile never fails
+		progs[i], _ = syntax.Compile(r.Simplify())
+	}
+	alphabet := verifyAlphabet(progs)
+	// -1 for the beginning or the end, and a character of each kind
+	contexts := []rune{-1, '\n', 'a', '!'}
+	var states []verifyState
+	seen := make(map[string]bool)
+	push := func(s verifyState) {
+		if len(s.pcs[0]) == 0 && len(s.pcs[1]) == 0 {
+			return
+		}
+		if key := verifyKey(s.pcs, s.prev); !seen[key] {
+			seen[key] = true
+			states = append(states, s)
+		}
+	}
+	for _, c := range contexts {
+		push(verifyState{
+			pcs:  [2][]uint32{{uint32(progs[0].Start)}, {uint32(progs[1].Start)}},
+			prev: c, parent: -1, r: c,
+		})
+	}
+	for i := 0; i < len(states); i++ {
+		if len(states) > maxVerifyStates {
+			return ErrTooManyStates
+		}
+		s := states[i]
+		insts := make(map[rune][2][]uint32, len(contexts))
+		for _, c := range contexts {
+			var xs [2][]uint32
+			var ms [2]bool
+			for j, p := range progs {
+				xs[j], ms[j] = closure(p, s.pcs[j], syntax.EmptyOpContext(s.prev, c))
+			}
+			if ms[0] != ms[1] {
+				return verifyError(states, i, c, ms[1])
+			}
+			insts[c] = xs
+		}
+		for _, c := range alphabet {
+			t := verifyState{prev: contextRune(c), parent: i, r: c}
+			for j, p := range progs {
+				for _, pc := range insts[t.prev][j] {
+					if p.Inst[pc].MatchRune(c) {
+						t.pcs[j] = append(t.pcs[j], p.Inst[pc].Out)
+					}
+				}
+				slices.Sort(t.pcs[j])
+				t.pcs[j] = slices.Compact(t.pcs[j])
+			}
+			push(t)
+		}
+	}
+	return nil
+}
+
+func verifyError(states []verifyState, i int, after rune, assembled bool) error {
+	var rs []rune
+	for ; states[i].parent >= 0; i = states[i].parent {
+		rs = append(rs, states[i].r)
+	}
+	slices.Reverse(rs)
+	err := &VerifyError{Text: string(rs), Assembled: assembled}
+	if c := states[i].r; c >= 0 {
+		err.Before = string(c)
+	}
+	if after >= 0 {
+		err.After = string(after)
+	}
+	return err
+}
+
+// closure returns the rune instructions reachable without consuming
+// a character, and whether the program matches there.
+func closure(p *syntax.Prog, pcs []uint32, flag syntax.EmptyOp) ([]uint32, bool) {
+	var insts []uint32
+	var match bool
+	visited := make([]bool, len(p.Inst))
+	stack := slices.Clone(pcs)
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[pc] {
+			continue
+		}
+		visited[pc] = true
+		switch inst := &p.Inst[pc]; inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, inst.Arg, inst.Out)
+		case syntax.InstCapture, syntax.InstNop:
+			stack = append(stack, inst.Out)
+		case syntax.InstEmptyWidth:
+			if syntax.EmptyOp(inst.Arg)&^flag == 0 {
+				stack = append(stack, inst.Out)
+			}
+		case syntax.InstMatch:
+			match = true
+		case syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			insts = append(insts, pc)
+		}
+	}
+	return insts, match
+}
+
+// verifyAlphabet returns a character of each range the programs do not
+// tell apart. The surrogates are skipped since they cannot be in strings.
+func verifyAlphabet(progs [2]*syntax.Prog) []rune {
+	bounds := []rune{0, '\n', '\n' + 1, '0', '9' + 1, 'A', 'Z' + 1, '_', '_' + 1, 'a', 'z' + 1}
+	for _, p := range progs {
+		for _, inst := range p.Inst {
+			switch inst.Op {
+			case syntax.InstRune, syntax.InstRune1:
+				for i := 0; i < len(inst.Rune); i++ {
+					lo, hi := inst.Rune[i], inst.Rune[i]
+					if i+1 < len(inst.Rune) {
+						i++
+						hi = inst.Rune[i]
+					}
+					bounds = append(bounds, lo, hi+1)
+					if len(inst.Rune) == 1 && syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+						for f := unicode.SimpleFold(lo); f != lo; f = unicode.SimpleFold(f) {
+							bounds = append(bounds, f, f+1)
+						}
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	rs := make([]rune, 0, len(bounds))
+	for i, c := range bounds {
+		if c > unicode.MaxRune {
+			break
+		}
+		if utf8.ValidRune(c) {
+			rs = append(rs, c)
+		} else if next := rune(0xe000); i+1 == len(bounds) || next < bounds[i+1] {
+			rs = append(rs, next)
+		}
+	}
+	return slices.Compact(rs)
+}
+
+// contextRune returns the character of the same kind for the assertions.
+func contextRune(c rune) rune {
+	switch {
+	case c == '\n':
+		return '\n'
+	case syntax.IsWordChar(c):
+		return 'a'
+	default:
+		return '!'
+	}
+}
+
+func verifyKey(pcs [2][]uint32, prev rune) string {
+	b := strconv.AppendInt(nil, int64(prev), 10)
+	for _, pcs := range pcs {
+		b = append(b, ';')
+		for _, pc := range pcs {
+			b = strconv.AppendUint(append(b, ','), uint64(pc), 10)
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,122 @@
+package rassemble
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	testCases := []struct {
+		name      string
+		patterns  []string
+		assembled string
+		err       string
+	}{
+		{
+			name:      "same literals",
+			patterns:  []string{"abc", "abd"},
+			assembled: "ab[cd]",
+		},
+		{
+			name:      "extra string",
+			patterns:  []string{"abc", "abd"},
+			assembled: "ab[c-e]",
+			err:       `the assembled pattern matches "abe" but the patterns do not`,
+		},
+		{
+			name:      "missing string",
+			patterns:  []string{"a", "ab", "abc"},
+			assembled: "a(?:bc)?",
+			err:       `the patterns match "ab" but the assembled pattern does not`,
+		},
+		{
+			name:      "case folding",
+			patterns:  []string{"(?i)k"},
+			assembled: "[Kk]",
+			err:       "the patterns match \"\u212a\" but the assembled pattern does not",
+		},
+		{
+			name:      "word boundary",
+			patterns:  []string{`\bfoo`},
+			assembled: "foo",
+			err:       `the assembled pattern matches "foo" after "a" but the patterns do not`,
+		},
+		{
+			name:      "line anchors",
+			patterns:  []string{`(?m:^a$)`, `\Ab\z`},
+			assembled: `(?m:^(?:a|\Ab)$)`,
+			err:       `the assembled pattern matches "b" before "\n" but the patterns do not`,
+		},
+		{
+			name:      "word boundaries on both sides",
+			patterns:  []string{`\ba`, `a\b`},
+			assembled: "a",
+			err:       `the assembled pattern matches "a" between "a" and "a" but the patterns do not`,
+		},
+		{
+			name:      "surrogates",
+			patterns:  []string{`[\x{d900}-\x{e100}]`},
+			assembled: `[\x{d800}-\x{e100}]`,
+		},
+		{
+			name:      "no patterns",
+			patterns:  []string{},
+			assembled: "",
+		},
+		{
+			name:      "no patterns but assembled",
+			patterns:  []string{},
+			assembled: "a",
+			err:       `the assembled pattern matches "a" but the patterns do not`,
+		},
+		{
+			name:      "invalid pattern",
+			patterns:  []string{"a", "("},
+			assembled: "a",
+			err:       "pattern 1: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			name:      "invalid assembled pattern",
+			patterns:  []string{"a"},
+			assembled: "(",
+			err:       "error parsing regexp: missing closing ): `(`",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.patterns, tc.assembled)
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("got an error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if err.Error() != tc.err {
+				t.Errorf("expected error: %s, got: %s", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyJoin(t *testing.T) {
+	for _, tc := range joinTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Verify(tc.patterns, tc.expected); err != nil {
+				var verr *VerifyError
+				if !errors.As(err, &verr) {
+					t.Fatalf("got an error: %s", err)
+				}
+				t.Errorf("%s: %s", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestVerifyTooManyStates(t *testing.T) {
+	if err := Verify([]string{"(?:a|b)*a(?:a|b){20}"}, "(?:a|b)*a(?:a|b){20}"); err != ErrTooManyStates {
+		t.Errorf("expected: %s, got: %v", ErrTooManyStates, err)
+	}
+}